@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
@@ -10,18 +11,22 @@ import (
 	fqdn_scheme "github.com/nais/liberator/pkg/apis/fqdnnetworkpolicies.networking.gke.io/v1alpha3"
 	"github.com/nais/liberator/pkg/logrus2logr"
 	liberator_scheme "github.com/nais/liberator/pkg/scheme"
+	"github.com/nais/naiserator/pkg/capabilities"
 	"github.com/nais/naiserator/pkg/controllers"
 	"github.com/nais/naiserator/pkg/generators"
+	"github.com/nais/naiserator/pkg/lifecycle"
 	"github.com/nais/naiserator/pkg/metrics"
 	"github.com/nais/naiserator/pkg/naiserator/config"
+	"github.com/nais/naiserator/pkg/policy"
 	"github.com/nais/naiserator/pkg/readonly"
 	naiserator_scheme "github.com/nais/naiserator/pkg/scheme"
 	"github.com/nais/naiserator/pkg/synchronizer"
+	"github.com/nais/naiserator/updater"
 	pov1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/discovery"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	ctrl "sigs.k8s.io/controller-runtime"
-	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	ctrl_log "sigs.k8s.io/controller-runtime/pkg/log"
 	kubemetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
@@ -56,13 +61,47 @@ func run() error {
 
 	config.Print([]string{})
 
-	if cfg.Features.Vault {
-		err = cfg.Vault.Validate()
+	err = cfg.Validate()
+	if err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	for _, warning := range cfg.Warnings() {
+		log.Warn(warning)
+	}
+
+	err = policy.Validate(cfg.PolicyRules)
+	if err != nil {
+		return err
+	}
+
+	kconfig, err := ctrl.GetConfig()
+	if err != nil {
+		return err
+	}
+	kconfig.QPS = float32(cfg.Ratelimit.QPS)
+	kconfig.Burst = cfg.Ratelimit.Burst
+
+	// Features are checked against the cluster before anything is set up, as the check may disable some of them.
+	var served capabilities.Served
+	if cfg.CapabilityCheck != config.CapabilityCheckOff {
+		discoveryClient, err := discovery.NewDiscoveryClientForConfig(kconfig)
+		if err != nil {
+			return err
+		}
+		served, err = capabilities.Discover(discoveryClient)
+		if err != nil {
+			return err
+		}
+		err = capabilities.Apply(cfg, served)
 		if err != nil {
 			return err
 		}
 	}
 
+	// Images, domains, destinations and proxy settings are reloaded when the configuration file changes.
+	liveConfig := config.NewLive(*cfg)
+	liveConfig.Watch()
+
 	// Register CRDs with controller-tools
 	kscheme, err := liberator_scheme.All()
 	if err != nil {
@@ -83,26 +122,39 @@ func run() error {
 		}
 	}
 
-	kconfig, err := ctrl.GetConfig()
+	metrics.Register(kubemetrics.Registry)
+	logSink := &logrus2logr.Logrus2Logr{Logger: log.StandardLogger()}
+	ctrl_log.SetLogger(logr.New(logSink))
+	// Each shard needs its own leader.
+	leaseName := cfg.ControllerLeaderElection.LeaseName
+	if cfg.Shard.Count > 1 {
+		leaseName = fmt.Sprintf("%s-shard-%d", leaseName, cfg.Shard.Index)
+	}
+
+	// The caches only hold the shard's namespaces and workloads. Hash sharding is left to the controller predicates,
+	// which along with the check in Reconcile also act as a safety net for the cache restrictions.
+	cacheOptions, err := shardCacheOptions(cfg.Shard)
 	if err != nil {
 		return err
 	}
-	kconfig.QPS = float32(cfg.Ratelimit.QPS)
-	kconfig.Burst = cfg.Ratelimit.Burst
+	cacheOptions.SyncPeriod = &cfg.Informer.FullSyncInterval
 
-	metrics.Register(kubemetrics.Registry)
-	logSink := &logrus2logr.Logrus2Logr{Logger: log.StandardLogger()}
-	ctrl_log.SetLogger(logr.New(logSink))
 	mgr, err := ctrl.NewManager(kconfig, ctrl.Options{
-		Cache: cache.Options{
-			SyncPeriod: &cfg.Informer.FullSyncInterval,
-		},
+		Cache:  cacheOptions,
 		Scheme: kscheme,
 		Metrics: metricsserver.Options{
 			BindAddress: cfg.Bind,
 		},
 		HealthProbeBindAddress: cfg.HealthProbeBindAddress,
 		Logger:                 logr.New(logSink),
+		// Standby replicas keep their caches warm, but only the leader runs controllers and rollout monitors.
+		LeaderElection:                cfg.ControllerLeaderElection.Enabled,
+		LeaderElectionID:              leaseName,
+		LeaderElectionNamespace:       cfg.ControllerLeaderElection.Namespace,
+		LeaderElectionReleaseOnCancel: true,
+		LeaseDuration:                 &cfg.ControllerLeaderElection.LeaseDuration,
+		RenewDeadline:                 &cfg.ControllerLeaderElection.RenewDeadline,
+		RetryPeriod:                   &cfg.ControllerLeaderElection.RetryPeriod,
 	})
 	if err != nil {
 		return err
@@ -121,10 +173,6 @@ func run() error {
 		}
 	}
 
-	if len(cfg.DomainIngressClassMapping) == 0 {
-		return fmt.Errorf("no gateway mappings defined. Will not be able to set the right gateway on the ingress")
-	}
-
 	listers := naiserator_scheme.GenericListers()
 	if cfg.Features.GCP {
 		listers = append(listers, naiserator_scheme.GCPListers()...)
@@ -134,7 +182,22 @@ func run() error {
 		}
 	}
 
-	mgrClient := mgr.GetClient()
+	if served != nil {
+		listers = capabilities.FilterListers(listers, kscheme, served)
+	}
+
+	// Owned resources are looked up by owner UID instead of by label, so that resources without labels are cleaned up too.
+	err = updater.IndexOwnerUID(context.Background(), mgr.GetFieldIndexer(), kscheme, listers)
+	if err != nil {
+		return err
+	}
+
+	// Lookups outside the shard, such as redirects and workloads in other shards, go directly to the API server.
+	mgrClient, err := newShardClient(mgr.GetClient(), mgr.GetAPIReader(), cfg.Shard)
+	if err != nil {
+		return err
+	}
+
 	simpleClient, err := client.New(kconfig, client.Options{
 		Scheme: kscheme,
 	})
@@ -143,20 +206,36 @@ func run() error {
 	}
 
 	if cfg.DryRun {
-		mgrClient = readonly.NewClient(mgrClient)
-		simpleClient = readonly.NewClient(simpleClient)
+		// Writes that would have been made are served on the metrics port for inspection.
+		recorder := readonly.NewRecorder(readonly.DefaultRecorderCapacity)
+		err = mgr.AddMetricsServerExtraHandler(readonly.RecorderPath, recorder)
+		if err != nil {
+			return err
+		}
+		mgrClient = readonly.NewRecordingClient(mgrClient, recorder)
+		simpleClient = readonly.NewRecordingClient(simpleClient, recorder)
+	}
+
+	lifecycleEvents := lifecycle.NewPublisherFromConfig(cfg.LifecycleEvents)
+	if lifecycleEvents != nil {
+		err = mgr.Add(lifecycleEvents)
+		if err != nil {
+			return err
+		}
 	}
 
-	applicationReconciler := controllers.NewAppReconciler(synchronizer.NewSynchronizer(
+	applicationSynchronizer := synchronizer.NewSynchronizer(
 		mgrClient,
 		simpleClient,
 		*cfg,
 		&generators.Application{
 			Config: *cfg,
+			Live:   liveConfig,
 		},
 		listers,
 		kscheme,
-	))
+	).WithLiveConfig(liveConfig).WithOwnerIndex().WithLifecycleEvents(lifecycleEvents)
+	applicationReconciler := controllers.NewAppReconciler(applicationSynchronizer)
 
 	opts := []controllers.Option{
 		controllers.WithMaxConcurrentReconciles(cfg.MaxConcurrentReconciles),
@@ -167,21 +246,31 @@ func run() error {
 		return err
 	}
 
-	naisjobReconciler := controllers.NewNaisjobReconciler(synchronizer.NewSynchronizer(
+	naisjobSynchronizer := synchronizer.NewSynchronizer(
 		mgrClient,
 		simpleClient,
 		*cfg,
 		&generators.Naisjob{
 			Config: *cfg,
+			Live:   liveConfig,
 		},
 		listers,
 		kscheme,
-	))
+	).WithLiveConfig(liveConfig).WithOwnerIndex().WithLifecycleEvents(lifecycleEvents)
+	naisjobReconciler := controllers.NewNaisjobReconciler(naisjobSynchronizer)
 
 	err = naisjobReconciler.SetupWithManager(mgr, cfg, opts...)
 	if err != nil {
 		return err
 	}
 
+	// Stop rollout monitors when leadership is lost, so that they are not duplicated by the new leader.
+	for _, runnable := range []*synchronizer.Synchronizer{applicationSynchronizer, naisjobSynchronizer} {
+		err = mgr.Add(runnable)
+		if err != nil {
+			return err
+		}
+	}
+
 	return mgr.Start(ctrl.SetupSignalHandler())
 }
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"slices"
+
+	nais_io_v1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+	nais_io_v1alpha1 "github.com/nais/liberator/pkg/apis/nais.io/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nais/naiserator/pkg/naiserator/config"
+)
+
+// shardCacheOptions restricts the caches to the shard's namespaces, and the workload caches to its label selector.
+// Sharding by hash can't be expressed as a cache restriction, and is handled by the controller predicates.
+func shardCacheOptions(shard config.Shard) (cache.Options, error) {
+	options := cache.Options{}
+	if !shard.Enabled() {
+		return options, nil
+	}
+
+	selector, err := shard.Selector()
+	if err != nil {
+		return options, err
+	}
+
+	if len(shard.Namespaces) > 0 {
+		options.DefaultNamespaces = make(map[string]cache.Config)
+		for _, namespace := range shard.Namespaces {
+			options.DefaultNamespaces[namespace] = cache.Config{}
+		}
+	}
+
+	if !selector.Empty() {
+		options.ByObject = map[client.Object]cache.ByObject{
+			&nais_io_v1alpha1.Application{}: {Label: selector},
+			&nais_io_v1.Naisjob{}:           {Label: selector},
+		}
+	}
+
+	return options, nil
+}
+
+// shardClient reads what the shard's caches hold from the caches, and everything else directly from the API server.
+// This covers resources that Naiserator manages outside the workload's namespace, such as service accounts and
+// Postgres network policies, and lookups across shards, such as redirects and name clashes between workloads.
+type shardClient struct {
+	client.Client
+	apiReader client.Reader
+	shard     config.Shard
+	selector  labels.Selector
+}
+
+func newShardClient(cli client.Client, apiReader client.Reader, shard config.Shard) (client.Client, error) {
+	if !shard.Enabled() {
+		return cli, nil
+	}
+
+	selector, err := shard.Selector()
+	if err != nil {
+		return nil, err
+	}
+
+	return &shardClient{
+		Client:    cli,
+		apiReader: apiReader,
+		shard:     shard,
+		selector:  selector,
+	}, nil
+}
+
+func (c *shardClient) cached(namespace string) bool {
+	return len(c.shard.Namespaces) == 0 || slices.Contains(c.shard.Namespaces, namespace)
+}
+
+func (c *shardClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if len(key.Namespace) > 0 && !c.cached(key.Namespace) {
+		return c.apiReader.Get(ctx, key, obj, opts...)
+	}
+
+	err := c.Client.Get(ctx, key, obj, opts...)
+	if errors.IsNotFound(err) && !c.selector.Empty() && isWorkload(obj) {
+		// Workloads that don't match the label selector are not cached.
+		return c.apiReader.Get(ctx, key, obj, opts...)
+	}
+	return err
+}
+
+func (c *shardClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	listOptions := &client.ListOptions{}
+	listOptions.ApplyOptions(opts)
+
+	cached := c.cached(listOptions.Namespace)
+	if len(listOptions.Namespace) == 0 {
+		// Lists across namespaces only use the cache for its field indexes, such as the owner index.
+		cached = len(c.shard.Namespaces) == 0 || listOptions.FieldSelector != nil
+	}
+
+	if !cached {
+		return c.apiReader.List(ctx, list, opts...)
+	}
+	return c.Client.List(ctx, list, opts...)
+}
+
+func isWorkload(obj client.Object) bool {
+	switch obj.(type) {
+	case *nais_io_v1alpha1.Application, *nais_io_v1.Naisjob:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,90 @@
+// Command naiserator_replay regenerates the resources for a rollout from a bundle recorded by Naiserator.
+//
+//	naiserator_replay bundle.json > resources.yaml
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	fqdn_scheme "github.com/nais/liberator/pkg/apis/fqdnnetworkpolicies.networking.gke.io/v1alpha3"
+	liberator_scheme "github.com/nais/liberator/pkg/scheme"
+	pov1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	log "github.com/sirupsen/logrus"
+	"sigs.k8s.io/yaml"
+
+	"github.com/nais/naiserator/pkg/generators"
+	"github.com/nais/naiserator/pkg/replay"
+	"github.com/nais/naiserator/pkg/synchronizer"
+)
+
+func main() {
+	err := run()
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	if len(os.Args) != 2 {
+		return fmt.Errorf("usage: %s <bundle.json>", os.Args[0])
+	}
+
+	bundle, err := replay.Load(os.Args[1])
+	if err != nil {
+		return err
+	}
+
+	kscheme, err := liberator_scheme.All()
+	if err != nil {
+		return err
+	}
+	err = fqdn_scheme.AddToScheme(kscheme)
+	if err != nil {
+		return err
+	}
+	err = pov1.AddToScheme(kscheme)
+	if err != nil {
+		return err
+	}
+
+	source, err := bundle.DecodeSource(kscheme)
+	if err != nil {
+		return err
+	}
+
+	kube, err := bundle.Client(kscheme)
+	if err != nil {
+		return err
+	}
+
+	var generator synchronizer.Generator
+	switch bundle.Kind {
+	case "Application":
+		generator = &generators.Application{Config: bundle.Config}
+	case "Naisjob":
+		generator = &generators.Naisjob{Config: bundle.Config}
+	default:
+		return fmt.Errorf("no generator for kind %q", bundle.Kind)
+	}
+
+	opts, err := generator.Prepare(context.Background(), source, kube)
+	if err != nil {
+		return fmt.Errorf("preparing rollout configuration: %w", err)
+	}
+
+	operations, err := generator.Generate(source, opts)
+	if err != nil {
+		return fmt.Errorf("generating resources: %w", err)
+	}
+
+	output, err := yaml.Marshal(operations)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stdout.Write(output)
+	return err
+}
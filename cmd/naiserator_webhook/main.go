@@ -9,12 +9,15 @@ import (
 	liberator_scheme "github.com/nais/liberator/pkg/scheme"
 	"github.com/nais/naiserator/pkg/metrics"
 	"github.com/nais/naiserator/pkg/naiserator/config"
+	"github.com/nais/naiserator/pkg/policy"
+	naiserator_webhook "github.com/nais/naiserator/pkg/webhook"
 	log "github.com/sirupsen/logrus"
 	ctrl "sigs.k8s.io/controller-runtime"
 	ctrl_log "sigs.k8s.io/controller-runtime/pkg/log"
 	kubemetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 )
@@ -47,6 +50,15 @@ func run() error {
 
 	config.Print([]string{})
 
+	// Defaults filled in by the mutator follow configuration reloads, like the generators do.
+	liveConfig := config.NewLive(*cfg)
+	liveConfig.Watch()
+
+	err = policy.Validate(cfg.PolicyRules)
+	if err != nil {
+		return err
+	}
+
 	// Register CRDs with controller-tools
 	kscheme, err := liberator_scheme.All()
 	if err != nil {
@@ -84,5 +96,23 @@ func run() error {
 		return err
 	}
 
+	// Register validation webhook for cluster-configured policy rules and deprecated inputs
+	mgr.GetWebhookServer().Register(naiserator_webhook.ValidatorPath, &webhook.Admission{
+		Handler: &naiserator_webhook.Validator{
+			Decoder: admission.NewDecoder(kscheme),
+			Rules:   cfg.PolicyRules,
+		},
+	})
+
+	// Register mutation webhook for deploy metadata and cluster-specific defaults
+	mgr.GetWebhookServer().Register(naiserator_webhook.MutatorPath, &webhook.Admission{
+		Handler: &naiserator_webhook.Mutator{
+			Decoder: admission.NewDecoder(kscheme),
+			Client:  mgr.GetAPIReader(),
+			Config:  *cfg,
+			Live:    liveConfig,
+		},
+	})
+
 	return mgr.Start(ctrl.SetupSignalHandler())
 }
@@ -0,0 +1,302 @@
+// Package annotations is the registry of nais.io annotations that change how Naiserator handles a workload,
+// with the values each of them accepts.
+package annotations
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/nais/naiserator/pkg/naiserator/config"
+)
+
+// Annotation keys read by Naiserator.
+const (
+	AcknowledgeDestructiveChanges = "nais.io/acknowledge-destructive-changes"
+	AllowRedirect                 = "nais.io/allow-redirect"
+	CanaryAnalysisQuery           = "naiserator.nais.io/canary-analysis-query"
+	CanaryStepInterval            = "naiserator.nais.io/canary-step-interval"
+	CanarySteps                   = "naiserator.nais.io/canary-steps"
+	ConfirmDeletion               = "nais.io/confirm-deletion"
+	DeletionProtection            = "nais.io/deletion-protection"
+	DeliveryStrategy              = "naiserator.nais.io/delivery-strategy"
+	DeployTimestamp               = "naiserator.nais.io/deploy-timestamp"
+	DeploymentCorrelationID       = "nais.io/deploymentCorrelationID"
+	FreezeOverride                = "nais.io/freeze-override"
+	Maintenance                   = "naiserator.nais.io/maintenance"
+	MaintenanceMessage            = "naiserator.nais.io/maintenance-message"
+	PodManagementPolicy           = "naiserator.nais.io/pod-management-policy"
+	Preview                       = "naiserator.nais.io/preview"
+	PreviewTTL                    = "naiserator.nais.io/preview-ttl"
+	ReadOnlyFileSystem            = "nais.io/read-only-file-system"
+	Record                        = "naiserator.nais.io/record"
+	RunAsGroup                    = "nais.io/run-as-group"
+	RunAsUser                     = "nais.io/run-as-user"
+	SidecarChannel                = "naiserator.nais.io/sidecar-channel"
+	SoftFail                      = "naiserator.nais.io/soft-fail"
+	VolumeClaims                  = "naiserator.nais.io/volume-claims"
+	WorkloadMode                  = "naiserator.nais.io/workload-mode"
+
+	NamespaceBetaFeatures              = "naiserator.nais.io/beta-features"
+	NamespaceDefaultLoggingDestination = "naiserator.nais.io/default-logging-destination"
+	NamespaceFQDNPolicy                = "naiserator.nais.io/fqdn-policy"
+	NamespaceHAProxyOnly               = "naiserator.nais.io/haproxy-only"
+	NamespaceImagePullSecrets          = "naiserator.nais.io/image-pull-secrets"
+)
+
+// Type decides which values an annotation accepts.
+type Type string
+
+const (
+	// Bool accepts the values understood by strconv.ParseBool.
+	Bool Type = "bool"
+	// Duration accepts a positive duration as understood by time.ParseDuration.
+	Duration Type = "duration"
+	// Enum accepts one of the annotation's values.
+	Enum Type = "enum"
+	// Integer accepts a non-negative integer.
+	Integer Type = "integer"
+	// List accepts a comma-separated list of the annotation's values.
+	List Type = "list"
+	// String accepts any non-empty value.
+	String Type = "string"
+	// Timestamp accepts an RFC 3339 timestamp.
+	Timestamp Type = "timestamp"
+	// VolumeClaimList accepts volume claims as parsed by ParseVolumeClaims.
+	VolumeClaimList Type = "volume-claims"
+	// WeightList accepts traffic weights as parsed by ParseWeights.
+	WeightList Type = "weights"
+)
+
+// Workload modes of Applications.
+const (
+	WorkloadModeDeployment  = "deployment"
+	WorkloadModeStatefulSet = "statefulset"
+)
+
+// Delivery strategies of Applications.
+const (
+	DeliveryStrategyRolling   = "rolling"
+	DeliveryStrategyCanary    = "canary"
+	DeliveryStrategyBlueGreen = "blue-green"
+)
+
+// Scope is the kind of object an annotation has an effect on.
+type Scope string
+
+const (
+	ScopeWorkload  Scope = "workload"
+	ScopeNamespace Scope = "namespace"
+)
+
+// Annotation describes a supported annotation.
+type Annotation struct {
+	Key         string
+	Type        Type
+	Scope       Scope
+	Values      []string
+	Description string
+}
+
+// Registry contains all annotations supported by Naiserator.
+var Registry = []Annotation{
+	{
+		Key:         AcknowledgeDestructiveChanges,
+		Type:        String,
+		Scope:       ScopeWorkload,
+		Description: "Correlation ID of the deployment whose destructive changes are acknowledged.",
+	},
+	{
+		Key:         AllowRedirect,
+		Type:        Enum,
+		Scope:       ScopeWorkload,
+		Values:      []string{"true", "false"},
+		Description: "Allows other applications to redirect from this application's ingresses.",
+	},
+	{
+		Key:         CanaryAnalysisQuery,
+		Type:        String,
+		Scope:       ScopeWorkload,
+		Description: "Prometheus comparison query that must return at least one sample for a canary step to pass.",
+	},
+	{
+		Key:         CanaryStepInterval,
+		Type:        Duration,
+		Scope:       ScopeWorkload,
+		Description: "How long each step of a canary or blue-green rollout lasts. Defaults to the cluster setting.",
+	},
+	{
+		Key:         CanarySteps,
+		Type:        WeightList,
+		Scope:       ScopeWorkload,
+		Description: "Percentages of ingress traffic sent to the canary, in order. Defaults to the cluster setting.",
+	},
+	{
+		Key:         ConfirmDeletion,
+		Type:        Bool,
+		Scope:       ScopeWorkload,
+		Description: "Confirms deletion of a protected workload and its stateful resources.",
+	},
+	{
+		Key:         DeletionProtection,
+		Type:        Bool,
+		Scope:       ScopeWorkload,
+		Description: "Overrides the cluster default for deletion protection.",
+	},
+	{
+		Key:         DeliveryStrategy,
+		Type:        Enum,
+		Scope:       ScopeWorkload,
+		Values:      []string{DeliveryStrategyRolling, DeliveryStrategyCanary, DeliveryStrategyBlueGreen},
+		Description: "Set to canary or blue-green to roll out new versions of an Application progressively.",
+	},
+	{
+		Key:         DeployTimestamp,
+		Type:        Timestamp,
+		Scope:       ScopeWorkload,
+		Description: "Time of the deployment, set by the admission webhook.",
+	},
+	{
+		Key:         DeploymentCorrelationID,
+		Type:        String,
+		Scope:       ScopeWorkload,
+		Description: "Identifies the deployment, set by NAIS deploy or the admission webhook.",
+	},
+	{
+		Key:         FreezeOverride,
+		Type:        String,
+		Scope:       ScopeWorkload,
+		Description: "Reason for rolling out during a freeze window.",
+	},
+	{
+		Key:         Maintenance,
+		Type:        Bool,
+		Scope:       ScopeWorkload,
+		Description: "Scales an Application down and serves a maintenance page on its ingresses.",
+	},
+	{
+		Key:         MaintenanceMessage,
+		Type:        String,
+		Scope:       ScopeWorkload,
+		Description: "Message shown to users while the Application is in maintenance mode.",
+	},
+	{
+		Key:         PodManagementPolicy,
+		Type:        Enum,
+		Scope:       ScopeWorkload,
+		Values:      []string{"OrderedReady", "Parallel"},
+		Description: "Whether the pods of a StatefulSet are started and stopped one at a time, or all at once.",
+	},
+	{
+		Key:         Preview,
+		Type:        Integer,
+		Scope:       ScopeWorkload,
+		Description: "Pull request number of an Application deployed as a preview of another Application.",
+	},
+	{
+		Key:         PreviewTTL,
+		Type:        Duration,
+		Scope:       ScopeWorkload,
+		Description: "How long a preview lives after its last deployment. Defaults to the cluster setting.",
+	},
+	{
+		Key:         ReadOnlyFileSystem,
+		Type:        Bool,
+		Scope:       ScopeWorkload,
+		Description: "Set to false to make the root file system of the containers writable.",
+	},
+	{
+		Key:         Record,
+		Type:        Bool,
+		Scope:       ScopeWorkload,
+		Description: "Records a replay bundle on every rollout, if recording is enabled in the cluster.",
+	},
+	{
+		Key:         RunAsGroup,
+		Type:        Integer,
+		Scope:       ScopeWorkload,
+		Description: "Group ID of the containers. Defaults to the user ID.",
+	},
+	{
+		Key:         RunAsUser,
+		Type:        Integer,
+		Scope:       ScopeWorkload,
+		Description: "User ID of the containers. Defaults to 1069.",
+	},
+	{
+		Key:         SidecarChannel,
+		Type:        String,
+		Scope:       ScopeWorkload,
+		Description: "Sidecar channel to take sidecar images from. Can also be set on the namespace.",
+	},
+	{
+		Key:         SoftFail,
+		Type:        List,
+		Scope:       ScopeWorkload,
+		Values:      append([]string{"*"}, config.OptionalIntegrations...),
+		Description: "Optional integrations that may fail without failing the rollout.",
+	},
+	{
+		Key:         VolumeClaims,
+		Type:        VolumeClaimList,
+		Scope:       ScopeWorkload,
+		Description: "Persistent volumes for each replica of a StatefulSet, as name=mountPath:size[:storageClass].",
+	},
+	{
+		Key:         WorkloadMode,
+		Type:        Enum,
+		Scope:       ScopeWorkload,
+		Values:      []string{WorkloadModeDeployment, WorkloadModeStatefulSet},
+		Description: "Set to statefulset to run an Application as a StatefulSet instead of a Deployment.",
+	},
+	{
+		Key:         NamespaceBetaFeatures,
+		Type:        String,
+		Scope:       ScopeNamespace,
+		Description: "Opts the namespace into beta features.",
+	},
+	{
+		Key:         NamespaceDefaultLoggingDestination,
+		Type:        String,
+		Scope:       ScopeNamespace,
+		Description: "Logging destination used when workloads don't specify any.",
+	},
+	{
+		Key:         NamespaceFQDNPolicy,
+		Type:        Bool,
+		Scope:       ScopeNamespace,
+		Description: "Set to false to disable FQDN network policies.",
+	},
+	{
+		Key:         NamespaceHAProxyOnly,
+		Type:        Bool,
+		Scope:       ScopeNamespace,
+		Description: "Creates only HAProxy ingresses for domains that have a HAProxy ingress class.",
+	},
+	{
+		Key:         NamespaceImagePullSecrets,
+		Type:        String,
+		Scope:       ScopeNamespace,
+		Description: "Image pull secrets in addition to the ones configured for the cluster.",
+	},
+}
+
+// ownedPrefixes are the key prefixes reserved for annotations in the registry.
+var ownedPrefixes = []string{"nais.io/", "naiserator.nais.io/"}
+
+// Lookup returns the registered annotation with the given key.
+func Lookup(key string) (Annotation, bool) {
+	i := slices.IndexFunc(Registry, func(annotation Annotation) bool {
+		return annotation.Key == key
+	})
+	if i < 0 {
+		return Annotation{}, false
+	}
+	return Registry[i], true
+}
+
+// Owned returns true if the key has a prefix reserved for annotations in the registry.
+func Owned(key string) bool {
+	return slices.ContainsFunc(ownedPrefixes, func(prefix string) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+}
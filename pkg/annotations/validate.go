@@ -0,0 +1,185 @@
+package annotations
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSuggestionDistance is the largest number of edits between an unknown key and a registered one
+// for the registered key to be suggested as a fix.
+const maxSuggestionDistance = 3
+
+// Problem is an annotation that is unknown, or has an invalid value.
+type Problem struct {
+	Key     string
+	Unknown bool
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("annotation %s: %s", p.Key, p.Message)
+}
+
+type Problems []Problem
+
+// Unknown returns problems with annotations that are not in the registry.
+func (p Problems) Unknown() Problems {
+	return p.filter(true)
+}
+
+// Invalid returns problems with invalid values of annotations in the registry.
+func (p Problems) Invalid() Problems {
+	return p.filter(false)
+}
+
+// Err returns an error describing all invalid values, or nil if there are none.
+func (p Problems) Err() error {
+	invalid := p.Invalid()
+	if len(invalid) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(invalid.Messages(), "; "))
+}
+
+// Messages returns a human-readable message for each problem.
+func (p Problems) Messages() []string {
+	messages := make([]string, 0, len(p))
+	for _, problem := range p {
+		messages = append(messages, problem.String())
+	}
+	return messages
+}
+
+func (p Problems) filter(unknown bool) Problems {
+	result := make(Problems, 0)
+	for _, problem := range p {
+		if problem.Unknown == unknown {
+			result = append(result, problem)
+		}
+	}
+	return result
+}
+
+// Validate checks the annotations of a workload against the registry.
+// Only keys with a reserved prefix are checked; other annotations are left alone.
+func Validate(annotations map[string]string) Problems {
+	keys := make([]string, 0, len(annotations))
+	for key := range annotations {
+		if Owned(key) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	problems := make(Problems, 0)
+	for _, key := range keys {
+		annotation, found := Lookup(key)
+		switch {
+		case !found:
+			problems = append(problems, Problem{Key: key, Unknown: true, Message: unknownMessage(key)})
+		case annotation.Scope != ScopeWorkload:
+			problems = append(problems, Problem{Key: key, Unknown: true, Message: fmt.Sprintf("only has an effect on %ss", annotation.Scope)})
+		default:
+			err := annotation.Validate(annotations[key])
+			if err != nil {
+				problems = append(problems, Problem{Key: key, Message: err.Error()})
+			}
+		}
+	}
+
+	return problems
+}
+
+// Validate returns an error if the value is not accepted by the annotation.
+func (a Annotation) Validate(value string) error {
+	switch a.Type {
+	case Bool:
+		_, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value %q; must be true or false", value)
+		}
+	case Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid value %q; must be a positive duration, e.g. 5m", value)
+		}
+	case Enum:
+		if !slices.Contains(a.Values, value) {
+			return fmt.Errorf("invalid value %q; must be one of %s", value, strings.Join(a.Values, ", "))
+		}
+	case Integer:
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || i < 0 {
+			return fmt.Errorf("invalid value %q; must be a non-negative integer", value)
+		}
+	case List:
+		for item := range strings.SplitSeq(value, ",") {
+			item = strings.TrimSpace(item)
+			if !slices.Contains(a.Values, item) {
+				return fmt.Errorf("invalid item %q; must be a comma-separated list of %s", item, strings.Join(a.Values, ", "))
+			}
+		}
+	case String:
+		if len(strings.TrimSpace(value)) == 0 {
+			return fmt.Errorf("must not be empty")
+		}
+	case Timestamp:
+		_, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return fmt.Errorf("invalid value %q; must be an RFC 3339 timestamp", value)
+		}
+	case VolumeClaimList:
+		_, err := ParseVolumeClaims(value)
+		return err
+	case WeightList:
+		_, err := ParseWeights(value)
+		return err
+	}
+	return nil
+}
+
+func unknownMessage(key string) string {
+	suggestion := ""
+	best := maxSuggestionDistance + 1
+	for _, annotation := range Registry {
+		if annotation.Scope != ScopeWorkload {
+			continue
+		}
+		distance := levenshtein(strings.ToLower(key), strings.ToLower(annotation.Key))
+		if distance < best {
+			suggestion, best = annotation.Key, distance
+		}
+	}
+
+	if len(suggestion) == 0 {
+		return "unknown annotation; it has no effect"
+	}
+	return fmt.Sprintf("unknown annotation; did you mean %s?", suggestion)
+}
+
+// levenshtein returns the number of single-character edits needed to turn a into b.
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
@@ -0,0 +1,94 @@
+package annotations_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nais/naiserator/pkg/annotations"
+)
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		expected    []string
+		invalid     int
+	}{
+		{
+			name: "valid annotations",
+			annotations: map[string]string{
+				annotations.RunAsUser:               "1000",
+				annotations.ReadOnlyFileSystem:      "false",
+				annotations.DeployTimestamp:         "2026-01-02T03:04:05Z",
+				annotations.SoftFail:                "postgres, aiven",
+				annotations.DeploymentCorrelationID: "abc",
+			},
+			expected: []string{},
+		},
+		{
+			name: "annotations without a reserved prefix are ignored",
+			annotations: map[string]string{
+				"kubernetes.io/change-cause": "deploy",
+				"azure.nais.io/preserve":     "true",
+			},
+			expected: []string{},
+		},
+		{
+			name: "invalid values",
+			annotations: map[string]string{
+				annotations.RunAsUser:          "root",
+				annotations.ReadOnlyFileSystem: "no",
+				annotations.AllowRedirect:      "yes",
+				annotations.SoftFail:           "postgres,redis",
+				annotations.FreezeOverride:     " ",
+				annotations.CanaryStepInterval: "-5m",
+			},
+			expected: []string{
+				`annotation nais.io/allow-redirect: invalid value "yes"; must be one of true, false`,
+				`annotation nais.io/freeze-override: must not be empty`,
+				`annotation nais.io/read-only-file-system: invalid value "no"; must be true or false`,
+				`annotation nais.io/run-as-user: invalid value "root"; must be a non-negative integer`,
+				`annotation naiserator.nais.io/canary-step-interval: invalid value "-5m"; must be a positive duration, e.g. 5m`,
+				`annotation naiserator.nais.io/soft-fail: invalid item "redis"; must be a comma-separated list of *, aiven, fqdn-policy, postgres, prometheus`,
+			},
+			invalid: 6,
+		},
+		{
+			name: "unknown keys",
+			annotations: map[string]string{
+				"nais.io/run-as-usr":            "1000",
+				"nais.io/seccomp":               "unconfined",
+				annotations.NamespaceFQDNPolicy: "false",
+			},
+			expected: []string{
+				`annotation nais.io/run-as-usr: unknown annotation; did you mean nais.io/run-as-user?`,
+				`annotation nais.io/seccomp: unknown annotation; it has no effect`,
+				`annotation naiserator.nais.io/fqdn-policy: only has an effect on namespaces`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			problems := annotations.Validate(tc.annotations)
+			assert.Equal(t, tc.expected, problems.Messages())
+			assert.Len(t, problems.Invalid(), tc.invalid)
+			if tc.invalid > 0 {
+				assert.Error(t, problems.Err())
+			} else {
+				assert.NoError(t, problems.Err())
+			}
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, annotation := range annotations.Registry {
+		assert.True(t, annotations.Owned(annotation.Key), annotation.Key)
+		assert.False(t, seen[annotation.Key], "duplicate key %s", annotation.Key)
+		assert.NotEmpty(t, annotation.Description, annotation.Key)
+		seen[annotation.Key] = true
+	}
+}
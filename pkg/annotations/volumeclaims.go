@@ -0,0 +1,68 @@
+package annotations
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	k8sResource "k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// VolumeClaim is a persistent volume created for each replica of a StatefulSet.
+type VolumeClaim struct {
+	Name         string
+	MountPath    string
+	Size         k8sResource.Quantity
+	StorageClass string
+}
+
+// ParseVolumeClaims parses a comma-separated list of volume claims on the form name=mountPath:size[:storageClass],
+// e.g. "data=/var/lib/data:10Gi:premium-rwo".
+func ParseVolumeClaims(value string) ([]VolumeClaim, error) {
+	claims := make([]VolumeClaim, 0)
+	names := make(map[string]bool)
+
+	for item := range strings.SplitSeq(value, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) == 0 {
+			continue
+		}
+
+		name, rest, found := strings.Cut(item, "=")
+		if !found {
+			return nil, fmt.Errorf("volume claim %q must be on the form name=mountPath:size[:storageClass]", item)
+		}
+		if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+			return nil, fmt.Errorf("volume claim name %q: %s", name, strings.Join(errs, "; "))
+		}
+		if names[name] {
+			return nil, fmt.Errorf("volume claim %q is defined more than once", name)
+		}
+		names[name] = true
+
+		parts := strings.Split(rest, ":")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("volume claim %q must be on the form name=mountPath:size[:storageClass]", item)
+		}
+
+		claim := VolumeClaim{Name: name, MountPath: parts[0]}
+		if !path.IsAbs(claim.MountPath) {
+			return nil, fmt.Errorf("volume claim %q: mount path %q must be absolute", name, claim.MountPath)
+		}
+
+		size, err := k8sResource.ParseQuantity(parts[1])
+		if err != nil || size.Sign() <= 0 {
+			return nil, fmt.Errorf("volume claim %q: invalid size %q", name, parts[1])
+		}
+		claim.Size = size
+
+		if len(parts) == 3 {
+			claim.StorageClass = parts[2]
+		}
+
+		claims = append(claims, claim)
+	}
+
+	return claims, nil
+}
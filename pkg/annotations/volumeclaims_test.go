@@ -0,0 +1,30 @@
+package annotations_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8sResource "k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/nais/naiserator/pkg/annotations"
+)
+
+func TestParseVolumeClaims(t *testing.T) {
+	claims, err := annotations.ParseVolumeClaims("data=/var/lib/data:10Gi:premium-rwo, logs=/var/log/app:1Gi")
+	assert.NoError(t, err)
+	assert.Equal(t, []annotations.VolumeClaim{
+		{Name: "data", MountPath: "/var/lib/data", Size: k8sResource.MustParse("10Gi"), StorageClass: "premium-rwo"},
+		{Name: "logs", MountPath: "/var/log/app", Size: k8sResource.MustParse("1Gi")},
+	}, claims)
+
+	for value, expected := range map[string]string{
+		"data":                          `volume claim "data" must be on the form name=mountPath:size[:storageClass]`,
+		"Data=/data:1Gi":                `volume claim name "Data"`,
+		"data=data:1Gi":                 `volume claim "data": mount path "data" must be absolute`,
+		"data=/data:lots":               `volume claim "data": invalid size "lots"`,
+		"data=/data:1Gi,data=/more:1Gi": `volume claim "data" is defined more than once`,
+	} {
+		_, err := annotations.ParseVolumeClaims(value)
+		assert.ErrorContains(t, err, expected, value)
+	}
+}
@@ -0,0 +1,27 @@
+package annotations
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseWeights parses a comma-separated list of traffic weights in percent, e.g. "10,25,50".
+// Weights must be between 1 and 99 and increase from one step to the next.
+func ParseWeights(value string) ([]int, error) {
+	weights := make([]int, 0)
+
+	for item := range strings.SplitSeq(value, ",") {
+		item = strings.TrimSpace(item)
+		weight, err := strconv.Atoi(item)
+		if err != nil || weight < 1 || weight > 99 {
+			return nil, fmt.Errorf("invalid weight %q; must be a percentage between 1 and 99", item)
+		}
+		if len(weights) > 0 && weight <= weights[len(weights)-1] {
+			return nil, fmt.Errorf("weight %d must be greater than the weight of the previous step", weight)
+		}
+		weights = append(weights, weight)
+	}
+
+	return weights, nil
+}
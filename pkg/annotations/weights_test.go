@@ -0,0 +1,26 @@
+package annotations_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nais/naiserator/pkg/annotations"
+)
+
+func TestParseWeights(t *testing.T) {
+	weights, err := annotations.ParseWeights("10, 25,50")
+	assert.NoError(t, err)
+	assert.Equal(t, []int{10, 25, 50}, weights)
+
+	for value, expected := range map[string]string{
+		"":        `invalid weight ""`,
+		"10,lots": `invalid weight "lots"`,
+		"0":       `invalid weight "0"`,
+		"100":     `invalid weight "100"`,
+		"50,25":   "weight 25 must be greater than the weight of the previous step",
+	} {
+		_, err := annotations.ParseWeights(value)
+		assert.ErrorContains(t, err, expected, value)
+	}
+}
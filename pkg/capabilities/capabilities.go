@@ -0,0 +1,263 @@
+// Package capabilities cross-checks the enabled features against the resource kinds served by the cluster,
+// so that a mismatch is reported at startup instead of as failures when workloads are synchronized.
+package capabilities
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	"github.com/nais/naiserator/pkg/naiserator/config"
+)
+
+// Served is the set of resource kinds served by the cluster.
+type Served struct {
+	kinds map[schema.GroupVersionKind]bool
+	// failed holds the group versions that could not be discovered, so that their kinds are unknown.
+	failed map[schema.GroupVersion]error
+}
+
+// Serves returns true if the cluster is known to serve the kind.
+func (s Served) Serves(gvk schema.GroupVersionKind) bool {
+	return s.kinds[gvk]
+}
+
+// Unknown returns true if the group version of the kind could not be discovered,
+// so that it is not known whether the cluster serves it.
+func (s Served) Unknown(gvk schema.GroupVersionKind) bool {
+	_, failed := s.failed[gvk.GroupVersion()]
+	return failed
+}
+
+// requirement ties a configuration setting to the resource kinds it creates.
+type requirement struct {
+	setting string
+	kinds   []schema.GroupVersionKind
+	enabled func(cfg config.Config) bool
+	disable func(cfg *config.Config)
+}
+
+func kinds(groupVersion string, kinds ...string) []schema.GroupVersionKind {
+	gv, _ := schema.ParseGroupVersion(groupVersion)
+	result := make([]schema.GroupVersionKind, 0, len(kinds))
+	for _, kind := range kinds {
+		result = append(result, gv.WithKind(kind))
+	}
+	return result
+}
+
+var requirements = []requirement{
+	{
+		setting: config.AivenProject,
+		kinds:   kinds("aiven.io/v1alpha1", "OpenSearch", "Valkey"),
+		enabled: func(cfg config.Config) bool { return len(cfg.AivenProject) > 0 },
+		disable: func(cfg *config.Config) { cfg.AivenProject = "" },
+	},
+	{
+		setting: config.FeaturesAzurerator,
+		kinds:   kinds("nais.io/v1", "AzureAdApplication"),
+		enabled: func(cfg config.Config) bool { return cfg.Features.Azurerator },
+		disable: func(cfg *config.Config) { cfg.Features.Azurerator = false },
+	},
+	{
+		setting: config.FeaturesCNRM,
+		kinds: append(append(
+			kinds("iam.cnrm.cloud.google.com/v1beta1", "IAMPolicy", "IAMPolicyMember", "IAMServiceAccount"),
+			kinds("sql.cnrm.cloud.google.com/v1beta1", "SQLDatabase", "SQLInstance", "SQLSSLCert", "SQLUser")...),
+			kinds("storage.cnrm.cloud.google.com/v1beta1", "StorageBucket", "StorageBucketAccessControl")...),
+		enabled: func(cfg config.Config) bool { return cfg.Features.CNRM },
+		disable: func(cfg *config.Config) { cfg.Features.CNRM = false },
+	},
+	{
+		setting: config.FQDNPolicyEnabled,
+		kinds:   kinds("networking.gke.io/v1alpha3", "FQDNNetworkPolicy"),
+		enabled: func(cfg config.Config) bool { return cfg.FQDNPolicy.Enabled },
+		disable: func(cfg *config.Config) { cfg.FQDNPolicy.Enabled = false },
+	},
+	{
+		setting: config.FeaturesIDPorten,
+		kinds:   kinds("nais.io/v1", "IDPortenClient"),
+		enabled: func(cfg config.Config) bool { return cfg.Features.IDPorten },
+		disable: func(cfg *config.Config) { cfg.Features.IDPorten = false },
+	},
+	{
+		setting: config.FeaturesJwker,
+		kinds:   kinds("nais.io/v1", "Jwker"),
+		enabled: func(cfg config.Config) bool { return cfg.Features.Jwker },
+		disable: func(cfg *config.Config) { cfg.Features.Jwker = false },
+	},
+	{
+		setting: config.FeaturesKafkarator,
+		kinds:   kinds("aiven.nais.io/v1", "AivenApplication"),
+		enabled: func(cfg config.Config) bool { return cfg.Features.Kafkarator },
+		disable: func(cfg *config.Config) { cfg.Features.Kafkarator = false },
+	},
+	{
+		setting: config.FeaturesMaskinporten,
+		kinds:   kinds("nais.io/v1", "MaskinportenClient"),
+		enabled: func(cfg config.Config) bool { return cfg.Features.Maskinporten },
+		disable: func(cfg *config.Config) { cfg.Features.Maskinporten = false },
+	},
+	{
+		setting: config.FeaturesPostgresOperator,
+		kinds:   kinds("data.nais.io/v1", "Postgres"),
+		enabled: func(cfg config.Config) bool { return cfg.Features.PostgresOperator },
+		disable: func(cfg *config.Config) { cfg.Features.PostgresOperator = false },
+	},
+	{
+		setting: config.FeaturesPrometheusOperator,
+		kinds:   kinds("monitoring.coreos.com/v1", "PodMonitor"),
+		enabled: func(cfg config.Config) bool { return cfg.Features.PrometheusOperator },
+		disable: func(cfg *config.Config) { cfg.Features.PrometheusOperator = false },
+	},
+}
+
+// Mismatch is an enabled setting whose resource kinds are not served by the cluster.
+type Mismatch struct {
+	Setting string
+	Missing []schema.GroupVersionKind
+	// Unknown is true if some of the missing kinds belong to group versions that could not be discovered.
+	Unknown bool
+}
+
+func (m Mismatch) String() string {
+	missing := make([]string, 0, len(m.Missing))
+	for _, gvk := range m.Missing {
+		missing = append(missing, gvk.Kind+"."+gvk.GroupVersion().String())
+	}
+	if m.Unknown {
+		return fmt.Sprintf("%s is enabled, but the cluster doesn't serve or couldn't discover %s", m.Setting, strings.Join(missing, ", "))
+	}
+	return fmt.Sprintf("%s is enabled, but the cluster doesn't serve %s", m.Setting, strings.Join(missing, ", "))
+}
+
+// Discover returns the resource kinds served by the cluster.
+// If some API groups can't be discovered, for example because an aggregated API server is down,
+// the kinds that could be discovered are returned along with a warning, and the kinds of the failed groups are unknown.
+func Discover(client discovery.DiscoveryInterface) (Served, error) {
+	served := Served{
+		kinds:  make(map[schema.GroupVersionKind]bool),
+		failed: make(map[schema.GroupVersion]error),
+	}
+
+	_, resources, err := client.ServerGroupsAndResources()
+	if err != nil {
+		groupErr, ok := err.(*discovery.ErrGroupDiscoveryFailed)
+		if !ok {
+			return Served{}, fmt.Errorf("discover served resources: %w", err)
+		}
+		log.Warnf("Capability check: %s", err)
+		maps.Copy(served.failed, groupErr.Groups)
+	}
+
+	for _, list := range resources {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, res := range list.APIResources {
+			served.kinds[gv.WithKind(res.Kind)] = true
+		}
+	}
+
+	return served, nil
+}
+
+// Check returns the enabled settings that need resource kinds the cluster doesn't serve.
+func Check(cfg config.Config, served Served) []Mismatch {
+	mismatches := make([]Mismatch, 0)
+
+	for _, req := range requirements {
+		if !req.enabled(cfg) {
+			continue
+		}
+		mismatch := Mismatch{Setting: req.setting}
+		for _, gvk := range req.kinds {
+			if !served.Serves(gvk) {
+				mismatch.Missing = append(mismatch.Missing, gvk)
+				mismatch.Unknown = mismatch.Unknown || served.Unknown(gvk)
+			}
+		}
+		if len(mismatch.Missing) > 0 {
+			mismatches = append(mismatches, mismatch)
+		}
+	}
+
+	return mismatches
+}
+
+// Apply reports mismatches between the configuration and the cluster according to cfg.CapabilityCheck.
+// In fail mode, an error with the full report is returned. In disable mode, the mismatched settings are
+// turned off in cfg, except for those whose API groups could not be discovered; they are left enabled with a warning,
+// so that an aggregated API server being down at startup doesn't turn off features for the lifetime of the process.
+func Apply(cfg *config.Config, served Served) error {
+	if cfg.CapabilityCheck == config.CapabilityCheckOff {
+		return nil
+	}
+
+	mismatches := Check(*cfg, served)
+	if len(mismatches) == 0 {
+		log.Infof("Capability check: all enabled features are supported by the cluster")
+		return nil
+	}
+
+	report := make([]string, 0, len(mismatches))
+	for _, mismatch := range mismatches {
+		report = append(report, mismatch.String())
+	}
+
+	switch cfg.CapabilityCheck {
+	case config.CapabilityCheckFail:
+		return fmt.Errorf("capability check failed: %s", strings.Join(report, "; "))
+	case config.CapabilityCheckDisable:
+		for i, mismatch := range mismatches {
+			if mismatch.Unknown {
+				log.Warnf("Capability check: %s; leaving %s enabled since discovery failed", report[i], mismatch.Setting)
+				continue
+			}
+			for _, req := range requirements {
+				if req.setting == mismatch.Setting {
+					req.disable(cfg)
+				}
+			}
+			log.Warnf("Capability check: %s; disabling %s", report[i], mismatch.Setting)
+		}
+	default:
+		for _, line := range report {
+			log.Warnf("Capability check: %s", line)
+		}
+	}
+
+	return nil
+}
+
+// FilterListers removes listers for kinds the cluster doesn't serve, so that the cache doesn't wait for
+// informers that can never sync. Kinds that are installed later are not cleaned up until Naiserator restarts.
+func FilterListers(listers []client.ObjectList, scheme *runtime.Scheme, served Served) []client.ObjectList {
+	result := make([]client.ObjectList, 0, len(listers))
+
+	for _, lister := range listers {
+		listGVK, err := apiutil.GVKForObject(lister, scheme)
+		if err != nil {
+			log.Warnf("Capability check: looking up GVK for lister %T: %s", lister, err)
+			continue
+		}
+
+		gvk := listGVK.GroupVersion().WithKind(strings.TrimSuffix(listGVK.Kind, "List"))
+		if !served.Serves(gvk) {
+			log.Warnf("Capability check: cluster doesn't serve %s; unreferenced resources of this kind will not be cleaned up", gvk)
+			continue
+		}
+
+		result = append(result, lister)
+	}
+
+	return result
+}
@@ -0,0 +1,114 @@
+package capabilities_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	clienttesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nais/naiserator/pkg/capabilities"
+	"github.com/nais/naiserator/pkg/naiserator/config"
+)
+
+func discover(t *testing.T) capabilities.Served {
+	client := &fakediscovery.FakeDiscovery{
+		Fake: &clienttesting.Fake{
+			Resources: []*metav1.APIResourceList{
+				{
+					GroupVersion: "v1",
+					APIResources: []metav1.APIResource{{Name: "configmaps", Kind: "ConfigMap"}},
+				},
+				{
+					GroupVersion: "monitoring.coreos.com/v1",
+					APIResources: []metav1.APIResource{{Name: "podmonitors", Kind: "PodMonitor"}},
+				},
+			},
+		},
+	}
+
+	served, err := capabilities.Discover(client)
+	assert.NoError(t, err)
+	return served
+}
+
+func TestCheck(t *testing.T) {
+	served := discover(t)
+	cfg := config.Config{
+		Features: config.Features{
+			PrometheusOperator: true,
+			PostgresOperator:   true,
+		},
+	}
+
+	mismatches := capabilities.Check(cfg, served)
+	assert.Len(t, mismatches, 1)
+	assert.Equal(t, "features.postgres-operator is enabled, but the cluster doesn't serve Postgres.data.nais.io/v1", mismatches[0].String())
+}
+
+func TestApply(t *testing.T) {
+	served := discover(t)
+
+	cfg := config.Config{
+		CapabilityCheck: config.CapabilityCheckFail,
+		FQDNPolicy:      config.FQDNPolicy{Enabled: true},
+	}
+	assert.ErrorContains(t, capabilities.Apply(&cfg, served), "capability check failed: fqdn-policy.enabled is enabled")
+
+	cfg.CapabilityCheck = config.CapabilityCheckWarn
+	assert.NoError(t, capabilities.Apply(&cfg, served))
+	assert.True(t, cfg.FQDNPolicy.Enabled)
+
+	cfg.CapabilityCheck = config.CapabilityCheckDisable
+	assert.NoError(t, capabilities.Apply(&cfg, served))
+	assert.False(t, cfg.FQDNPolicy.Enabled)
+}
+
+// failingDiscovery fails to discover some group versions, like when an aggregated API server is down.
+type failingDiscovery struct {
+	*fakediscovery.FakeDiscovery
+	failed map[schema.GroupVersion]error
+}
+
+func (d *failingDiscovery) ServerGroupsAndResources() ([]*metav1.APIGroup, []*metav1.APIResourceList, error) {
+	groups, resources, err := d.FakeDiscovery.ServerGroupsAndResources()
+	if err != nil {
+		return nil, nil, err
+	}
+	return groups, resources, &discovery.ErrGroupDiscoveryFailed{Groups: d.failed}
+}
+
+func TestApplyDiscoveryFailed(t *testing.T) {
+	client := &failingDiscovery{
+		FakeDiscovery: &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{}},
+		failed: map[schema.GroupVersion]error{
+			{Group: "networking.gke.io", Version: "v1alpha3"}: fmt.Errorf("the server is currently unable to handle the request"),
+		},
+	}
+	served, err := capabilities.Discover(client)
+	assert.NoError(t, err)
+
+	cfg := config.Config{
+		CapabilityCheck: config.CapabilityCheckDisable,
+		FQDNPolicy:      config.FQDNPolicy{Enabled: true},
+		Features:        config.Features{PostgresOperator: true},
+	}
+	assert.NoError(t, capabilities.Apply(&cfg, served))
+	assert.True(t, cfg.FQDNPolicy.Enabled, "kinds of groups that couldn't be discovered are not known to be missing")
+	assert.False(t, cfg.Features.PostgresOperator)
+}
+
+func TestFilterListers(t *testing.T) {
+	served := discover(t)
+	listers := []client.ObjectList{&corev1.ConfigMapList{}, &corev1.SecretList{}}
+
+	filtered := capabilities.FilterListers(listers, scheme.Scheme, served)
+	assert.Equal(t, []client.ObjectList{&corev1.ConfigMapList{}}, filtered)
+}
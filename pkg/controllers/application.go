@@ -31,8 +31,14 @@ func (r *ApplicationReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 }
 
 func (r *ApplicationReconciler) SetupWithManager(mgr ctrl.Manager, cfg *config.Config, opts ...Option) error {
+	inShardNamespace, inShard, err := shardPredicates(cfg.Shard)
+	if err != nil {
+		return err
+	}
+
 	controllerBuilder := ctrl.NewControllerManagedBy(mgr).
-		For(&nais_io_v1alpha1.Application{}).
+		WithEventFilter(inShardNamespace).
+		For(&nais_io_v1alpha1.Application{}, builder.WithPredicates(inShard)).
 		Watches(&nais_io_v1.Image{}, handler.EnqueueRequestsFromMapFunc(mapImageToApplicationOrNaisjob))
 
 	if cfg.Features.PostgresOperator {
@@ -30,8 +30,14 @@ func (r *NaisjobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 }
 
 func (r *NaisjobReconciler) SetupWithManager(mgr ctrl.Manager, cfg *config.Config, opts ...Option) error {
+	inShardNamespace, inShard, err := shardPredicates(cfg.Shard)
+	if err != nil {
+		return err
+	}
+
 	controllerBuilder := ctrl.NewControllerManagedBy(mgr).
-		For(&nais_io_v1.Naisjob{}).
+		WithEventFilter(inShardNamespace).
+		For(&nais_io_v1.Naisjob{}, builder.WithPredicates(inShard)).
 		Watches(&nais_io_v1.Image{}, handler.EnqueueRequestsFromMapFunc(mapImageToApplicationOrNaisjob))
 
 	if cfg.Features.PostgresOperator {
@@ -0,0 +1,29 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/nais/naiserator/pkg/naiserator/config"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// shardPredicates returns a predicate for events in namespaces belonging to the shard,
+// and a predicate for workloads matching the shard's label selector.
+func shardPredicates(shard config.Shard) (predicate.Predicate, predicate.Predicate, error) {
+	selector, err := shard.Selector()
+	if err != nil {
+		return nil, nil, fmt.Errorf("shard label selector: %w", err)
+	}
+
+	inNamespace := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return shard.OwnsNamespace(obj.GetNamespace())
+	})
+
+	matchesLabels := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return selector.Matches(labels.Set(obj.GetLabels()))
+	})
+
+	return inNamespace, matchesLabels, nil
+}
@@ -0,0 +1,112 @@
+// Package deprecation keeps track of legacy inputs that Naiserator still supports,
+// and when support for each of them will be removed.
+package deprecation
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/nais/naiserator/pkg/metrics"
+)
+
+const sunsetLayout = "2006-01-02"
+
+// Deprecation describes a legacy input and its replacement.
+type Deprecation struct {
+	ID          string
+	Field       string
+	Replacement string
+	Sunset      time.Time
+}
+
+var (
+	PreStopHookPath = Deprecation{
+		ID:          "pre-stop-hook-path",
+		Field:       "spec.preStopHookPath",
+		Replacement: "spec.preStopHook.http.path",
+		Sunset:      sunset("2027-01-31"),
+	}
+	CPUThresholdPercentage = Deprecation{
+		ID:          "cpu-threshold-percentage",
+		Field:       "spec.replicas.cpuThresholdPercentage",
+		Replacement: "spec.replicas.scalingStrategy.cpu.thresholdPercentage",
+		Sunset:      sunset("2027-03-31"),
+	}
+	IDPortenWithoutSidecar = Deprecation{
+		ID:          "idporten-without-sidecar",
+		Field:       "spec.idporten.enabled without spec.idporten.sidecar.enabled",
+		Replacement: "spec.idporten.sidecar.enabled",
+		Sunset:      sunset("2027-01-31"),
+	}
+	NginxOnlyAnnotations = Deprecation{
+		ID:          "nginx-only-annotations",
+		Field:       "nginx.ingress.kubernetes.io annotations without a HAProxy equivalent",
+		Replacement: "haproxy.org annotations",
+		Sunset:      sunset("2027-06-30"),
+	}
+)
+
+// Registry contains all known deprecations.
+var Registry = []Deprecation{
+	PreStopHookPath,
+	CPUThresholdPercentage,
+	IDPortenWithoutSidecar,
+	NginxOnlyAnnotations,
+}
+
+// Usage is a deprecated input found in a workload.
+type Usage struct {
+	Deprecation
+	// Detail optionally narrows down which part of the input is deprecated, e.g. annotation keys.
+	Detail string
+}
+
+func (u Usage) Message() string {
+	field := u.Field
+	if len(u.Detail) > 0 {
+		field = fmt.Sprintf("%s (%s)", field, u.Detail)
+	}
+	return fmt.Sprintf("%s is deprecated and support will be removed after %s; use %s instead", field, u.Sunset.Format(sunsetLayout), u.Replacement)
+}
+
+// Messages returns a human-readable message for each usage.
+func Messages(usages []Usage) []string {
+	messages := make([]string, 0, len(usages))
+	for _, usage := range usages {
+		messages = append(messages, usage.Message())
+	}
+	return messages
+}
+
+// Report replaces the deprecation metrics for a workload with the given usages.
+func Report(kind, namespace, name string, usages []Usage) {
+	Forget(kind, namespace, name)
+	for _, usage := range usages {
+		metrics.DeprecatedInputs.With(prometheus.Labels{
+			"kind":        kind,
+			"team":        namespace,
+			"name":        name,
+			"deprecation": usage.ID,
+			"sunset":      usage.Sunset.Format(sunsetLayout),
+		}).Set(1)
+	}
+}
+
+// Forget removes all deprecation metrics for a workload.
+func Forget(kind, namespace, name string) {
+	metrics.DeprecatedInputs.DeletePartialMatch(prometheus.Labels{
+		"kind": kind,
+		"team": namespace,
+		"name": name,
+	})
+}
+
+func sunset(date string) time.Time {
+	t, err := time.Parse(sunsetLayout, date)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
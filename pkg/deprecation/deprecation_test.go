@@ -0,0 +1,44 @@
+package deprecation_test
+
+import (
+	"testing"
+
+	nais_io_v1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nais/naiserator/pkg/deprecation"
+	"github.com/nais/naiserator/pkg/test/fixtures"
+)
+
+func TestDetect(t *testing.T) {
+	t.Run("defaulted application uses no deprecated inputs", func(t *testing.T) {
+		app := fixtures.MinimalApplication()
+		assert.Empty(t, deprecation.Detect(app))
+	})
+
+	t.Run("all deprecated inputs are detected", func(t *testing.T) {
+		app := fixtures.MinimalApplication(
+			fixtures.WithAnnotation("nginx.ingress.kubernetes.io/proxy-body-size", "8m"),
+			fixtures.WithAnnotation("nginx.ingress.kubernetes.io/proxy-read-timeout", "300"),
+		)
+		app.Spec.PreStopHookPath = "/stop"
+		app.Spec.Replicas.CpuThresholdPercentage = 80
+		app.Spec.IDPorten = &nais_io_v1.IDPorten{Enabled: true}
+		app.Spec.Ingresses = []nais_io_v1.Ingress{"https://myapplication.nav.no"}
+
+		usages := deprecation.Detect(app)
+		assert.Equal(t, []string{
+			"spec.preStopHookPath is deprecated and support will be removed after 2027-01-31; use spec.preStopHook.http.path instead",
+			"spec.replicas.cpuThresholdPercentage is deprecated and support will be removed after 2027-03-31; use spec.replicas.scalingStrategy.cpu.thresholdPercentage instead",
+			"spec.idporten.enabled without spec.idporten.sidecar.enabled is deprecated and support will be removed after 2027-01-31; use spec.idporten.sidecar.enabled instead",
+			"nginx.ingress.kubernetes.io annotations without a HAProxy equivalent (nginx.ingress.kubernetes.io/proxy-body-size) is deprecated and support will be removed after 2027-06-30; use haproxy.org annotations instead",
+		}, deprecation.Messages(usages))
+	})
+
+	t.Run("nginx annotations are ignored without ingresses", func(t *testing.T) {
+		app := fixtures.MinimalApplication(
+			fixtures.WithAnnotation("nginx.ingress.kubernetes.io/proxy-body-size", "8m"),
+		)
+		assert.Empty(t, deprecation.Detect(app))
+	})
+}
@@ -0,0 +1,63 @@
+package deprecation
+
+import (
+	"strings"
+
+	nais_io_v1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+
+	"github.com/nais/naiserator/pkg/resourcecreator/ingress"
+	"github.com/nais/naiserator/pkg/resourcecreator/resource"
+)
+
+// The value filled in by ApplyDefaults.
+// It cannot be distinguished from an explicitly configured threshold, and is thus not reported.
+const defaultCPUThresholdPercentage = 50
+
+type preStopHookSource interface {
+	GetPreStopHookPath() string
+}
+
+type replicaSource interface {
+	GetReplicas() *nais_io_v1.Replicas
+}
+
+type idportenSource interface {
+	GetIDPorten() *nais_io_v1.IDPorten
+}
+
+type ingressSource interface {
+	GetIngress() []nais_io_v1.Ingress
+}
+
+// Detect returns all deprecated inputs used by an Application or Naisjob.
+func Detect(source resource.Source) []Usage {
+	usages := make([]Usage, 0)
+
+	if src, ok := source.(preStopHookSource); ok && len(src.GetPreStopHookPath()) > 0 {
+		usages = append(usages, Usage{Deprecation: PreStopHookPath})
+	}
+
+	if src, ok := source.(replicaSource); ok {
+		replicas := src.GetReplicas()
+		//lint:ignore SA1019 we are looking for usage of the deprecated field
+		if replicas != nil && replicas.CpuThresholdPercentage > 0 && replicas.CpuThresholdPercentage != defaultCPUThresholdPercentage {
+			usages = append(usages, Usage{Deprecation: CPUThresholdPercentage})
+		}
+	}
+
+	if src, ok := source.(idportenSource); ok {
+		idporten := src.GetIDPorten()
+		if idporten != nil && idporten.Enabled && (idporten.Sidecar == nil || !idporten.Sidecar.Enabled) {
+			usages = append(usages, Usage{Deprecation: IDPortenWithoutSidecar})
+		}
+	}
+
+	if src, ok := source.(ingressSource); ok && len(src.GetIngress()) > 0 {
+		keys := ingress.NginxOnlyAnnotations(source.GetAnnotations())
+		if len(keys) > 0 {
+			usages = append(usages, Usage{Deprecation: NginxOnlyAnnotations, Detail: strings.Join(keys, ", ")})
+		}
+	}
+
+	return usages
+}
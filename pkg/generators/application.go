@@ -17,8 +17,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/nais/naiserator/pkg/naiserator/config"
+	"github.com/nais/naiserator/pkg/progressive"
 	"github.com/nais/naiserator/pkg/resourcecreator/aiven"
 	"github.com/nais/naiserator/pkg/resourcecreator/azure"
+	"github.com/nais/naiserator/pkg/resourcecreator/canary"
 	"github.com/nais/naiserator/pkg/resourcecreator/certificateauthority"
 	"github.com/nais/naiserator/pkg/resourcecreator/deployment"
 	"github.com/nais/naiserator/pkg/resourcecreator/fqdnpolicy"
@@ -37,16 +39,26 @@ import (
 	"github.com/nais/naiserator/pkg/resourcecreator/resource"
 	"github.com/nais/naiserator/pkg/resourcecreator/service"
 	"github.com/nais/naiserator/pkg/resourcecreator/serviceaccount"
+	"github.com/nais/naiserator/pkg/resourcecreator/statefulset"
 	"github.com/nais/naiserator/pkg/resourcecreator/vault"
 	"github.com/nais/naiserator/pkg/synchronizer"
 )
 
 type Application struct {
 	Config config.Config
+	// Live, when set, takes precedence over Config so that reloaded configuration is used.
+	Live *config.Live
 }
 
 var _ synchronizer.Generator = &Application{}
 
+func (g *Application) config() config.Config {
+	if g.Live != nil {
+		return g.Live.Get()
+	}
+	return g.Config
+}
+
 // Prepare a configuration context for further processing.
 // This function detects run-time parameters from a live running cluster.
 func (g *Application) Prepare(ctx context.Context, source resource.Source, kube client.Client) (any, error) {
@@ -56,7 +68,13 @@ func (g *Application) Prepare(ctx context.Context, source resource.Source, kube
 	}
 
 	o := &Options{
-		Config: g.Config,
+		Config: g.config(),
+	}
+
+	// This should be first, so that the rest of the preparation sees the spec of the preview.
+	err := preparePreview(ctx, app, kube)
+	if err != nil {
+		return nil, err
 	}
 
 	// Make a query to Kubernetes for this application's previous deployment.
@@ -66,10 +84,29 @@ func (g *Application) Prepare(ctx context.Context, source resource.Source, kube
 		Name:      source.GetName(),
 		Namespace: source.GetNamespace(),
 	}
-	deploy := &appsv1.Deployment{}
-	err := kube.Get(ctx, key, deploy)
-	if err != nil && !errors.IsNotFound(err) {
-		return nil, fmt.Errorf("query existing deployment: %s", err)
+	var currentReplicas *int32
+	var deploy *appsv1.Deployment
+	if statefulset.Enabled(app) {
+		statefulSet := &appsv1.StatefulSet{}
+		err := kube.Get(ctx, key, statefulSet)
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, fmt.Errorf("query existing statefulset: %s", err)
+		}
+		currentReplicas = statefulSet.Spec.Replicas
+		if err == nil {
+			o.livePodSpec = &statefulSet.Spec.Template.Spec
+		}
+	} else {
+		deploy = &appsv1.Deployment{}
+		err := kube.Get(ctx, key, deploy)
+		if errors.IsNotFound(err) {
+			deploy = nil
+		} else if err != nil {
+			return nil, fmt.Errorf("query existing deployment: %s", err)
+		} else {
+			currentReplicas = deploy.Spec.Replicas
+			o.livePodSpec = &deploy.Spec.Template.Spec
+		}
 	}
 
 	// Disallow creating application resources if there is a Naisjob with the same name.
@@ -79,7 +116,8 @@ func (g *Application) Prepare(ctx context.Context, source resource.Source, kube
 		return nil, fmt.Errorf("cannot create an Application with name '%s' because a Naisjob with that name exists", source.GetName())
 	}
 
-	o.NumReplicas = numReplicas(deploy, app.GetReplicas().Min, app.GetReplicas().Max)
+	o.NumReplicas = numReplicas(currentReplicas, app.GetReplicas().Min, app.GetReplicas().Max)
+	prepareMaintenance(app, o)
 
 	// Retrieve current namespace to check for labels and annotations
 	namespaceKey := client.ObjectKey{Name: source.GetNamespace()}
@@ -97,24 +135,42 @@ func (g *Application) Prepare(ctx context.Context, source resource.Source, kube
 	// Auto-detect Google Team Project ID
 	o.GoogleTeamProjectID = namespace.Annotations["cnrm.cloud.google.com/project-id"]
 
+	err = applyNamespaceOverrides(namespace, o)
+	if err != nil {
+		return nil, err
+	}
+
+	err = applySidecarChannel(source, namespace, o)
+	if err != nil {
+		return nil, err
+	}
+
 	err = prepareSqlInstance(ctx, source, kube, o)
 	if err != nil {
 		return nil, err
 	}
 
-	err = preparePostgres(ctx, app, kube, o)
+	o.WorkloadSoftFail = source.GetAnnotations()[synchronizer.SoftFailAnnotation]
+
+	err = o.softFail(config.IntegrationPostgres, preparePostgres(ctx, app, kube, o))
+	if err != nil {
+		return nil, err
+	}
+
+	err = prepareProgressiveDelivery(ctx, app, kube, deploy, o)
 	if err != nil {
 		return nil, err
 	}
 
 	o.Team = app.GetNamespace()
+	o.WorkloadName = app.GetName()
 
 	return o, nil
 }
 
 // Generate transforms an Application resource into a set of Kubernetes resources,
 // along with information about what to do with these resources, i.e. CreateOrUpdate, etc.
-func (g *Application) Generate(source resource.Source, config any) (resource.Operations, error) {
+func (g *Application) Generate(source resource.Source, options any) (resource.Operations, error) {
 	var err error
 
 	app, ok := source.(*nais_io_v1alpha1.Application)
@@ -122,7 +178,7 @@ func (g *Application) Generate(source resource.Source, config any) (resource.Ope
 		return nil, fmt.Errorf("BUG: CreateApplication only accepts nais_io_v1alpha1.Application objects; fix your code")
 	}
 
-	cfg, ok := config.(*Options)
+	cfg, ok := options.(*Options)
 	if !ok {
 		return nil, fmt.Errorf("BUG: Application generator called without correct configuration object; fix your code")
 	}
@@ -134,7 +190,10 @@ func (g *Application) Generate(source resource.Source, config any) (resource.Ope
 
 	service.Create(app, ast, cfg)
 	serviceaccount.Create(app, ast, cfg)
-	horizontalpodautoscaler.Create(app, ast)
+	if !cfg.IsMaintenanceEnabled() {
+		// The autoscaler would scale the application back up.
+		horizontalpodautoscaler.Create(app, ast)
+	}
 	networkpolicy.Create(app, ast, cfg)
 	fqdnpolicy.Create(app, ast, cfg)
 
@@ -179,7 +238,9 @@ func (g *Application) Generate(source resource.Source, config any) (resource.Ope
 	}
 
 	if cfg.PostgresOperatorEnabled() {
-		err = postgres.Create(app, ast, cfg)
+		err = cfg.optional(config.IntegrationPostgres, ast, func(ast *resource.Ast) error {
+			return postgres.Create(app, ast, cfg)
+		})
 		if err != nil {
 			return nil, err
 		}
@@ -200,7 +261,9 @@ func (g *Application) Generate(source resource.Source, config any) (resource.Ope
 
 	tokenxclient := jwker.Create(app, ast, cfg)
 
-	err = aiven.Create(app, ast, cfg)
+	err = cfg.optional(config.IntegrationAiven, ast, func(ast *resource.Ast) error {
+		return aiven.Create(app, ast, cfg)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -225,12 +288,20 @@ func (g *Application) Generate(source resource.Source, config any) (resource.Ope
 		return nil, err
 	}
 
-	err = deployment.Create(app, ast, cfg)
+	switch {
+	case statefulset.Enabled(app):
+		err = statefulset.Create(app, ast, cfg)
+	case progressive.Enabled(app.GetAnnotations()):
+		err = canary.Create(app, ast, cfg)
+	default:
+		err = deployment.Create(app, ast, cfg)
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	podmonitor.Create(app, ast, cfg)
+	cfg.keepLivePodSpec(ast.Operations)
 
 	return ast.Operations, nil
 }
@@ -5,7 +5,12 @@ import (
 	"slices"
 	"strings"
 
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+
 	"github.com/nais/naiserator/pkg/naiserator/config"
+	"github.com/nais/naiserator/pkg/progressive"
+	"github.com/nais/naiserator/pkg/synchronizer"
 )
 
 type SqlInstance struct {
@@ -25,6 +30,19 @@ type Options struct {
 	Team                  string
 	SqlInstance           SqlInstance
 	PostgresClusterEngine string
+	HAProxyOnly           bool
+	BetaFeatures          []string
+	WorkloadName          string
+	WorkloadSoftFail      string
+	SidecarChannel        string
+	Progressive           *progressive.State
+	StableDeployment      *appsv1.Deployment
+	Maintenance           bool
+	MaintenanceMessage    string
+
+	degradations []synchronizer.Degradation
+	// livePodSpec is the pod spec of the running workload, if any, see keepLivePodSpec.
+	livePodSpec *corev1.PodSpec
 }
 
 func (o *Options) GetAccessPolicyNotAllowedCIDRs() []string {
@@ -89,6 +107,15 @@ func (o *Options) GetIngressClasses(domain string) ([]string, error) {
 		)
 	}
 
+	if o.HAProxyOnly {
+		haproxyClasses := slices.DeleteFunc(slices.Clone(classes), func(class string) bool {
+			return !strings.HasSuffix(class, "haproxy")
+		})
+		if len(haproxyClasses) > 0 {
+			return haproxyClasses, nil
+		}
+	}
+
 	return classes, nil
 }
 
@@ -126,6 +153,14 @@ func (o *Options) GetLeaderElectionImage() string {
 	return o.Config.LeaderElection.Image
 }
 
+func (o *Options) GetMaintenanceBackend() string {
+	return o.Config.Maintenance.Backend
+}
+
+func (o *Options) GetMaintenanceMessage() string {
+	return o.MaintenanceMessage
+}
+
 func (o *Options) GetNaisNamespace() string {
 	return o.Config.NaisNamespace
 }
@@ -138,6 +173,14 @@ func (o *Options) GetObservability() config.Observability {
 	return o.Config.Observability
 }
 
+func (o *Options) GetProgressiveState() *progressive.State {
+	return o.Progressive
+}
+
+func (o *Options) GetStableDeployment() *appsv1.Deployment {
+	return o.StableDeployment
+}
+
 func (o *Options) GetTeam() string {
 	return o.Team
 }
@@ -178,6 +221,16 @@ func (o *Options) IsJwkerEnabled() bool {
 	return o.Config.Features.Jwker
 }
 
+// IsBetaFeatureEnabled returns true if the namespace has opted into the named beta feature.
+func (o *Options) IsBetaFeatureEnabled(feature string) bool {
+	return slices.Contains(o.BetaFeatures, feature)
+}
+
+// IsFeatureGateEnabled returns true if the named feature gate is open for the workload being generated.
+func (o *Options) IsFeatureGateEnabled(gate string) bool {
+	return o.Config.FeatureGate(gate).Enabled(o.Team, o.WorkloadName)
+}
+
 func (o *Options) IsHAProxyEnabled() bool {
 	return o.Config.Features.HAProxy
 }
@@ -186,6 +239,10 @@ func (o *Options) IsKafkaratorEnabled() bool {
 	return o.Config.Features.Kafkarator
 }
 
+func (o *Options) IsMaintenanceEnabled() bool {
+	return o.Maintenance
+}
+
 func (o *Options) IsMaskinportenEnabled() bool {
 	return o.Config.Features.Maskinporten
 }
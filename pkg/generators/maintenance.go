@@ -0,0 +1,25 @@
+package generators
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/nais/naiserator/pkg/annotations"
+	"github.com/nais/naiserator/pkg/resourcecreator/resource"
+)
+
+// prepareMaintenance scales an Application in maintenance mode down, while its ingresses serve a maintenance page.
+func prepareMaintenance(source resource.Source, o *Options) {
+	// Invalid values are reported by annotations.Validate, and leave maintenance mode off.
+	enabled, err := strconv.ParseBool(source.GetAnnotations()[annotations.Maintenance])
+	if err != nil || !enabled {
+		return
+	}
+
+	o.Maintenance = true
+	o.NumReplicas = 0
+	o.MaintenanceMessage = source.GetAnnotations()[annotations.MaintenanceMessage]
+	if len(o.MaintenanceMessage) == 0 {
+		o.MaintenanceMessage = fmt.Sprintf("%s is down for maintenance.", source.GetName())
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/nais/naiserator/pkg/resourcecreator/texas"
 	"github.com/nais/naiserator/pkg/resourcecreator/vault"
 	"github.com/nais/naiserator/pkg/synchronizer"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -31,10 +32,19 @@ import (
 
 type Naisjob struct {
 	Config config.Config
+	// Live, when set, takes precedence over Config so that reloaded configuration is used.
+	Live *config.Live
 }
 
 var _ synchronizer.Generator = &Naisjob{}
 
+func (g *Naisjob) config() config.Config {
+	if g.Live != nil {
+		return g.Live.Get()
+	}
+	return g.Config
+}
+
 // Generate a configuration context for further processing.
 // This function detects run-time parameters from a live running cluster.
 func (g *Naisjob) Prepare(ctx context.Context, source resource.Source, kube client.Client) (any, error) {
@@ -44,7 +54,7 @@ func (g *Naisjob) Prepare(ctx context.Context, source resource.Source, kube clie
 	}
 
 	o := &Options{
-		Config: g.Config,
+		Config: g.config(),
 	}
 
 	o.NumReplicas = 1
@@ -68,33 +78,54 @@ func (g *Naisjob) Prepare(ctx context.Context, source resource.Source, kube clie
 		return nil, fmt.Errorf("cannot create a Naisjob with name '%s' because an Application with that name exists", source.GetName())
 	}
 
+	cronJob := &batchv1.CronJob{}
+	err = kube.Get(ctx, key, cronJob)
+	if err == nil {
+		o.livePodSpec = &cronJob.Spec.JobTemplate.Spec.Template.Spec
+	} else if !errors.IsNotFound(err) {
+		return nil, fmt.Errorf("query existing cronjob: %s", err)
+	}
+
 	// Auto-detect Google Team Project ID
 	o.GoogleTeamProjectID = namespace.Annotations["cnrm.cloud.google.com/project-id"]
 
+	err = applyNamespaceOverrides(namespace, o)
+	if err != nil {
+		return nil, err
+	}
+
+	err = applySidecarChannel(source, namespace, o)
+	if err != nil {
+		return nil, err
+	}
+
 	err = prepareSqlInstance(ctx, source, kube, o)
 	if err != nil {
 		return nil, err
 	}
 
-	err = preparePostgres(ctx, job, kube, o)
+	o.WorkloadSoftFail = source.GetAnnotations()[synchronizer.SoftFailAnnotation]
+
+	err = o.softFail(config.IntegrationPostgres, preparePostgres(ctx, job, kube, o))
 	if err != nil {
 		return nil, err
 	}
 
 	o.Team = job.GetNamespace()
+	o.WorkloadName = job.GetName()
 
 	return o, nil
 }
 
 // CreateNaisjob takes an Naisjob resource and returns a slice of Kubernetes resources
 // along with information about what to do with these resources.
-func (g *Naisjob) Generate(source resource.Source, config any) (resource.Operations, error) {
+func (g *Naisjob) Generate(source resource.Source, options any) (resource.Operations, error) {
 	naisjob, ok := source.(*nais_io_v1.Naisjob)
 	if !ok {
 		return nil, fmt.Errorf("BUG: generator only accepts nais_io_v1.Naisjob objects, fix your caller")
 	}
 
-	cfg, ok := config.(*Options)
+	cfg, ok := options.(*Options)
 	if !ok {
 		return nil, fmt.Errorf("BUG: Application generator called without correct configuration object; fix your code")
 	}
@@ -120,7 +151,9 @@ func (g *Naisjob) Generate(source resource.Source, config any) (resource.Operati
 	}
 
 	if cfg.PostgresOperatorEnabled() {
-		err = postgres.Create(naisjob, ast, cfg)
+		err = cfg.optional(config.IntegrationPostgres, ast, func(ast *resource.Ast) error {
+			return postgres.Create(naisjob, ast, cfg)
+		})
 		if err != nil {
 			return nil, err
 		}
@@ -136,7 +169,9 @@ func (g *Naisjob) Generate(source resource.Source, config any) (resource.Operati
 		return nil, err
 	}
 
-	err = aiven.Create(naisjob, ast, cfg)
+	err = cfg.optional(config.IntegrationAiven, ast, func(ast *resource.Ast) error {
+		return aiven.Create(naisjob, ast, cfg)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -162,6 +197,7 @@ func (g *Naisjob) Generate(source resource.Source, config any) (resource.Operati
 	if err := batch.CreateCronJob(naisjob, ast, cfg); err != nil {
 		return nil, err
 	}
+	cfg.keepLivePodSpec(ast.Operations)
 
 	return ast.Operations, nil
 }
@@ -0,0 +1,106 @@
+package generators
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/nais/naiserator/pkg/annotations"
+	"github.com/nais/naiserator/pkg/naiserator/config"
+)
+
+// Namespace labels or annotations that override cluster configuration for all workloads in a namespace.
+// Annotations take precedence over labels. List values are comma-separated, and thus only usable as annotations.
+const (
+	// NamespaceFQDNPolicy set to "false" disables FQDN network policies.
+	NamespaceFQDNPolicy = annotations.NamespaceFQDNPolicy
+	// NamespaceHAProxyOnly set to "true" creates only HAProxy ingresses for domains that have a HAProxy ingress class.
+	NamespaceHAProxyOnly = annotations.NamespaceHAProxyOnly
+	// NamespaceImagePullSecrets adds image pull secrets to the ones configured for the cluster.
+	NamespaceImagePullSecrets = annotations.NamespaceImagePullSecrets
+	// NamespaceDefaultLoggingDestination replaces the logging destination used when workloads don't specify any.
+	NamespaceDefaultLoggingDestination = annotations.NamespaceDefaultLoggingDestination
+	// NamespaceBetaFeatures opts the namespace into beta features.
+	NamespaceBetaFeatures = annotations.NamespaceBetaFeatures
+	// NamespaceSidecarChannel selects the sidecar channel for workloads that don't select one themselves.
+	NamespaceSidecarChannel = annotations.SidecarChannel
+)
+
+func namespaceValue(namespace *corev1.Namespace, key string) (string, bool) {
+	if value, found := namespace.GetAnnotations()[key]; found {
+		return value, true
+	}
+	value, found := namespace.GetLabels()[key]
+	return value, found
+}
+
+func namespaceList(namespace *corev1.Namespace, key string) []string {
+	value, _ := namespaceValue(namespace, key)
+	list := make([]string, 0)
+	for item := range strings.SplitSeq(value, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) > 0 {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func namespaceBool(namespace *corev1.Namespace, key string) (value bool, found bool, err error) {
+	str, found := namespaceValue(namespace, key)
+	if !found {
+		return false, false, nil
+	}
+	value, err = strconv.ParseBool(str)
+	if err != nil {
+		return false, true, fmt.Errorf("namespace %s: invalid value %q for %s: %w", namespace.GetName(), str, key, err)
+	}
+	return value, true, nil
+}
+
+// applyNamespaceOverrides merges namespace-level overrides into the options.
+// Overrides can only restrict or extend cluster features; they cannot enable features the cluster doesn't support.
+func applyNamespaceOverrides(namespace *corev1.Namespace, o *Options) error {
+	fqdnPolicy, found, err := namespaceBool(namespace, NamespaceFQDNPolicy)
+	if err != nil {
+		return err
+	}
+	if found && !fqdnPolicy {
+		o.Config.FQDNPolicy.Enabled = false
+	}
+
+	haproxyOnly, _, err := namespaceBool(namespace, NamespaceHAProxyOnly)
+	if err != nil {
+		return err
+	}
+	o.HAProxyOnly = haproxyOnly && o.Config.Features.HAProxy
+
+	// Copy to avoid modifying the cluster configuration
+	o.Config.ImagePullSecrets = slices.Concat(o.Config.ImagePullSecrets, namespaceList(namespace, NamespaceImagePullSecrets))
+
+	destination, err := DefaultLoggingDestination(o.Config, namespace)
+	if err != nil {
+		return err
+	}
+	o.Config.Observability.Logging.DefaultDestination = destination
+
+	o.BetaFeatures = namespaceList(namespace, NamespaceBetaFeatures)
+
+	return nil
+}
+
+// DefaultLoggingDestination returns the logging destination used for workloads in the namespace that don't specify any,
+// which is the cluster default unless the namespace overrides it.
+func DefaultLoggingDestination(cfg config.Config, namespace *corev1.Namespace) (string, error) {
+	destination, found := namespaceValue(namespace, NamespaceDefaultLoggingDestination)
+	if !found {
+		return cfg.Observability.Logging.DefaultDestination, nil
+	}
+	if !slices.Contains(cfg.Observability.Logging.Destinations, destination) {
+		return "", fmt.Errorf("namespace %s: logging destination %q does not exist in cluster", namespace.GetName(), destination)
+	}
+	return destination, nil
+}
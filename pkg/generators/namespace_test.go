@@ -0,0 +1,106 @@
+package generators
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/nais/naiserator/pkg/naiserator/config"
+)
+
+func TestApplyNamespaceOverrides(t *testing.T) {
+	clusterConfig := func() config.Config {
+		cfg := config.Config{
+			DomainIngressClassMapping: []config.GatewayMapping{
+				{DomainSuffix: ".nais.io", IngressClass: "nais-ingress"},
+				{DomainSuffix: ".nais.io", IngressClass: "nais-ingress-haproxy"},
+			},
+			ImagePullSecrets: []string{"cluster-secret"},
+		}
+		cfg.Features.HAProxy = true
+		cfg.FQDNPolicy.Enabled = true
+		cfg.Observability.Logging.Destinations = []string{"loki", "elastic"}
+		return cfg
+	}
+
+	t.Run("no overrides", func(t *testing.T) {
+		o := &Options{Config: clusterConfig()}
+		err := applyNamespaceOverrides(&corev1.Namespace{}, o)
+		assert.NoError(t, err)
+		assert.True(t, o.GetFQDNPolicy().Enabled)
+		assert.Equal(t, []string{"cluster-secret"}, o.GetImagePullSecrets())
+		assert.Empty(t, o.GetObservability().Logging.DefaultDestination)
+
+		classes, err := o.GetIngressClasses("myapp.nais.io")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"nais-ingress", "nais-ingress-haproxy"}, classes)
+	})
+
+	t.Run("all overrides", func(t *testing.T) {
+		cfg := clusterConfig()
+		o := &Options{Config: cfg}
+		namespace := &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "myteam",
+				Labels: map[string]string{
+					NamespaceFQDNPolicy:  "false",
+					NamespaceHAProxyOnly: "false",
+				},
+				Annotations: map[string]string{
+					NamespaceHAProxyOnly:               "true",
+					NamespaceImagePullSecrets:          "team-secret, other-secret",
+					NamespaceDefaultLoggingDestination: "elastic",
+					NamespaceBetaFeatures:              "foo,bar",
+				},
+			},
+		}
+
+		err := applyNamespaceOverrides(namespace, o)
+		assert.NoError(t, err)
+		assert.False(t, o.GetFQDNPolicy().Enabled)
+		assert.Equal(t, []string{"cluster-secret", "team-secret", "other-secret"}, o.GetImagePullSecrets())
+		assert.Equal(t, "elastic", o.GetObservability().Logging.DefaultDestination)
+		assert.True(t, o.IsBetaFeatureEnabled("bar"))
+		assert.False(t, o.IsBetaFeatureEnabled("baz"))
+
+		classes, err := o.GetIngressClasses("myapp.nais.io")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"nais-ingress-haproxy"}, classes)
+
+		// The cluster configuration is left untouched.
+		assert.Equal(t, []string{"cluster-secret"}, cfg.ImagePullSecrets)
+	})
+
+	t.Run("haproxy only without haproxy enabled", func(t *testing.T) {
+		o := &Options{Config: clusterConfig()}
+		o.Config.Features.HAProxy = false
+		namespace := &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{NamespaceHAProxyOnly: "true"},
+			},
+		}
+
+		err := applyNamespaceOverrides(namespace, o)
+		assert.NoError(t, err)
+		assert.False(t, o.HAProxyOnly)
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		for key, value := range map[string]string{
+			NamespaceFQDNPolicy:                "maybe",
+			NamespaceHAProxyOnly:               "yes please",
+			NamespaceDefaultLoggingDestination: "splunk",
+		} {
+			o := &Options{Config: clusterConfig()}
+			namespace := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "myteam",
+					Annotations: map[string]string{key: value},
+				},
+			}
+			assert.Error(t, applyNamespaceOverrides(namespace, o), key)
+		}
+	})
+}
@@ -0,0 +1,92 @@
+package generators
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+
+	nais_io_v1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+	nais_io_v1alpha1 "github.com/nais/liberator/pkg/apis/nais.io/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nais/naiserator/pkg/annotations"
+	"github.com/nais/naiserator/pkg/preview"
+)
+
+// previewIgnoredAnnotations are removed from previews, which always run as a Deployment and are rolled out at once.
+var previewIgnoredAnnotations = []string{
+	annotations.DeliveryStrategy,
+	annotations.VolumeClaims,
+	annotations.WorkloadMode,
+}
+
+// preparePreview turns an Application into a preview of its parent Application.
+// The preview shares the parent's access policy and secrets by reference, gets its own hostnames,
+// and skips stateful resources, which belong to the parent.
+func preparePreview(ctx context.Context, app *nais_io_v1alpha1.Application, kube client.Client) error {
+	pullRequest := app.GetAnnotations()[annotations.Preview]
+	if len(pullRequest) == 0 {
+		return nil
+	}
+
+	parentName, err := preview.ParentName(app.GetName(), pullRequest)
+	if err != nil {
+		return err
+	}
+
+	key := client.ObjectKey{
+		Name:      parentName,
+		Namespace: app.GetNamespace(),
+	}
+	parent := &nais_io_v1alpha1.Application{}
+	err = kube.Get(ctx, key, parent)
+	if errors.IsNotFound(err) {
+		return fmt.Errorf("cannot create a preview of '%s' because no Application with that name exists", parentName)
+	} else if err != nil {
+		return fmt.Errorf("query parent application: %s", err)
+	}
+
+	if preview.Enabled(parent.GetAnnotations()) {
+		return fmt.Errorf("cannot create a preview of '%s' because it is a preview itself", parentName)
+	}
+
+	app.Spec.AccessPolicy = parent.Spec.AccessPolicy.DeepCopy()
+	app.Spec.EnvFrom = slices.Clone(parent.Spec.EnvFrom)
+	app.Spec.FilesFrom = slices.Clone(parent.Spec.FilesFrom)
+
+	if app.Spec.GCP != nil {
+		app.Spec.GCP.BigQueryDatasets = nil
+		app.Spec.GCP.Buckets = nil
+		app.Spec.GCP.SqlInstances = nil
+	}
+	app.Spec.OpenSearch = nil
+	app.Spec.Postgres = nil
+	app.Spec.Valkey = nil
+
+	objectAnnotations := app.GetAnnotations()
+	for _, key := range previewIgnoredAnnotations {
+		delete(objectAnnotations, key)
+	}
+	app.SetAnnotations(objectAnnotations)
+
+	// Redirects are served by the parent.
+	app.Spec.Redirects = nil
+
+	ingresses := make([]nais_io_v1.Ingress, 0, len(app.Spec.Ingresses))
+	for _, ingress := range app.Spec.Ingresses {
+		ingressURL, err := url.Parse(string(ingress))
+		if err != nil {
+			return fmt.Errorf("preview ingress '%s': %s", ingress, err)
+		}
+		ingressURL.Host = preview.Host(ingressURL.Host, app.GetName())
+		previewIngress := nais_io_v1.Ingress(ingressURL.String())
+		if !slices.Contains(ingresses, previewIngress) {
+			ingresses = append(ingresses, previewIngress)
+		}
+	}
+	app.Spec.Ingresses = ingresses
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package generators
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	nais_io_v1alpha1 "github.com/nais/liberator/pkg/apis/nais.io/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nais/naiserator/pkg/annotations"
+	"github.com/nais/naiserator/pkg/progressive"
+	"github.com/nais/naiserator/pkg/resourcecreator/canary"
+	"github.com/nais/naiserator/pkg/resourcecreator/statefulset"
+	"github.com/nais/naiserator/pkg/synchronizer"
+)
+
+var _ synchronizer.ProgressiveSource = &Options{}
+
+// prepareProgressiveDelivery decides the next step of a canary or blue-green rollout.
+// There is nothing to roll out progressively until the stable Deployment runs pods labelled with their track,
+// so the first rollout after enabling progressive delivery is a regular one.
+// Applications in maintenance mode are scaled down at once, and a canary in progress is rolled back.
+func prepareProgressiveDelivery(ctx context.Context, app *nais_io_v1alpha1.Application, kube client.Client, stable *appsv1.Deployment, o *Options) error {
+	if !progressive.Enabled(app.GetAnnotations()) {
+		return nil
+	}
+
+	if o.Maintenance {
+		return abortProgressiveDelivery(ctx, app, kube, stable, o)
+	}
+
+	if statefulset.Enabled(app) {
+		return fmt.Errorf("progressive delivery is only supported for applications running as Deployments")
+	}
+
+	settings, err := progressive.NewSettings(app.GetAnnotations(), o.Config.ProgressiveDelivery)
+	if err != nil {
+		return err
+	}
+
+	observed, err := observeProgressiveDelivery(ctx, app, kube, stable)
+	if err != nil || observed == nil {
+		return err
+	}
+
+	var analysis progressive.Analysis
+	if len(settings.Query) > 0 {
+		analysis, err = progressive.PrometheusAnalysis(o.Config.ProgressiveDelivery.PrometheusURL)
+		if err != nil {
+			return err
+		}
+	}
+
+	state := progressive.Next(ctx, settings, *observed, time.Now(), analysis)
+	o.Progressive = &state
+	o.StableDeployment = stable
+
+	return nil
+}
+
+// abortProgressiveDelivery ends the rollout of an Application that enters maintenance mode, see progressive.Abort.
+func abortProgressiveDelivery(ctx context.Context, app *nais_io_v1alpha1.Application, kube client.Client, stable *appsv1.Deployment, o *Options) error {
+	observed, err := observeProgressiveDelivery(ctx, app, kube, stable)
+	if err != nil || observed == nil {
+		return err
+	}
+
+	state, ok := progressive.Abort(*observed)
+	if !ok {
+		return nil
+	}
+	o.Progressive = &state
+	o.StableDeployment = stable
+
+	return nil
+}
+
+// observeProgressiveDelivery reads the progress of the rollout from the cluster and the status of the Application.
+// It returns nil if there is nothing to roll out progressively.
+func observeProgressiveDelivery(ctx context.Context, app *nais_io_v1alpha1.Application, kube client.Client, stable *appsv1.Deployment) (*progressive.Observed, error) {
+	if stable == nil || stable.Spec.Template.Labels[progressive.TrackLabel] != progressive.TrackStable {
+		return nil, nil
+	}
+
+	key := client.ObjectKey{
+		Name:      canary.Name(app),
+		Namespace: app.GetNamespace(),
+	}
+	canaryDeployment := &appsv1.Deployment{}
+	err := kube.Get(ctx, key, canaryDeployment)
+	if errors.IsNotFound(err) {
+		canaryDeployment = nil
+	} else if err != nil {
+		return nil, fmt.Errorf("query existing canary deployment: %s", err)
+	}
+
+	if canaryDeployment == nil && stable.GetAnnotations()[annotations.DeploymentCorrelationID] == app.CorrelationID() {
+		// Already promoted; the Application is synchronized again without a new deployment.
+		return nil, nil
+	}
+
+	observed := progressive.Observe(canaryDeployment, stable, app.CorrelationID())
+
+	status := app.GetStatus()
+	if status.Conditions != nil && status.CorrelationID == app.CorrelationID() {
+		condition := meta.FindStatusCondition(*status.Conditions, synchronizer.ConditionProgressiveDelivery)
+		if condition != nil && condition.Reason == string(progressive.PhaseRolledBack) {
+			observed.Phase = progressive.PhaseRolledBack
+			observed.Message = condition.Message
+		}
+	}
+
+	return &observed, nil
+}
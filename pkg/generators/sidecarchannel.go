@@ -0,0 +1,38 @@
+package generators
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/nais/naiserator/pkg/annotations"
+	"github.com/nais/naiserator/pkg/naiserator/config"
+	"github.com/nais/naiserator/pkg/resourcecreator/resource"
+	"github.com/nais/naiserator/pkg/synchronizer"
+)
+
+var _ synchronizer.SidecarImageSource = &Options{}
+
+// applySidecarChannel takes the sidecar images from the channel selected by the workload, or else by its namespace.
+func applySidecarChannel(source resource.Source, namespace *corev1.Namespace, o *Options) error {
+	channel, found := source.GetAnnotations()[annotations.SidecarChannel]
+	if !found {
+		channel, _ = namespaceValue(namespace, NamespaceSidecarChannel)
+	}
+
+	cfg, err := o.Config.WithSidecarChannel(channel)
+	if err != nil {
+		return err
+	}
+
+	o.Config = cfg
+	o.SidecarChannel = channel
+	if len(o.SidecarChannel) == 0 {
+		o.SidecarChannel = config.DefaultSidecarChannel
+	}
+
+	return nil
+}
+
+// SidecarImages returns the sidecar channel and the images taken from it.
+func (o *Options) SidecarImages() (string, map[string]string) {
+	return o.SidecarChannel, o.Config.SidecarImages()
+}
@@ -0,0 +1,123 @@
+package generators
+
+import (
+	"reflect"
+	"slices"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nais/naiserator/pkg/resourcecreator/resource"
+	"github.com/nais/naiserator/pkg/synchronizer"
+)
+
+var _ synchronizer.Degradable = &Options{}
+
+// softFail records the error as a degradation and swallows it, if the integration may fail without failing the rollout.
+func (o *Options) softFail(integration string, err error) error {
+	if err == nil || !o.Config.SoftFail(integration, o.WorkloadSoftFail) {
+		return err
+	}
+
+	o.degradations = append(o.degradations, synchronizer.Degradation{
+		Integration: integration,
+		Message:     err.Error(),
+	})
+
+	return nil
+}
+
+func (o *Options) degraded(integration string) bool {
+	return slices.ContainsFunc(o.degradations, func(degradation synchronizer.Degradation) bool {
+		return degradation.Integration == integration
+	})
+}
+
+// optional runs an integration's resource generator, leaving the AST untouched if the integration soft-fails,
+// or has already soft-failed during Prepare. The live objects of a skipped integration are kept by the synchronizer,
+// and its part of the pod spec is kept by keepLivePodSpec.
+func (o *Options) optional(integration string, ast *resource.Ast, create func(ast *resource.Ast) error) error {
+	if o.degraded(integration) {
+		return nil
+	}
+
+	attempt := ast.Clone()
+	err := create(attempt)
+	if err != nil {
+		return o.softFail(integration, err)
+	}
+
+	*ast = *attempt
+
+	return nil
+}
+
+// keepLivePodSpec carries the environment, volumes and volume mounts of the running workload over to the generated one
+// when integrations were skipped, so that the pods keep the configuration of the integrations that are still running.
+// Everything missing from the generated pod spec is carried over, as the skipped integrations can't tell what they would
+// have added. The pod spec is generated from scratch again once the integrations are healthy.
+func (o *Options) keepLivePodSpec(operations resource.Operations) {
+	if len(o.degradations) == 0 || o.livePodSpec == nil {
+		return
+	}
+
+	for _, operation := range operations {
+		podSpec := workloadPodSpec(operation.Resource)
+		if podSpec != nil {
+			mergePodSpec(podSpec, o.livePodSpec, o.WorkloadName)
+		}
+	}
+}
+
+func workloadPodSpec(obj client.Object) *corev1.PodSpec {
+	switch workload := obj.(type) {
+	case *appsv1.Deployment:
+		return &workload.Spec.Template.Spec
+	case *appsv1.StatefulSet:
+		return &workload.Spec.Template.Spec
+	case *batchv1.CronJob:
+		return &workload.Spec.JobTemplate.Spec.Template.Spec
+	default:
+		return nil
+	}
+}
+
+// mergePodSpec adds the volumes of the live pod spec, and the environment and volume mounts of its application container,
+// that are missing from the desired pod spec.
+func mergePodSpec(desired, live *corev1.PodSpec, containerName string) {
+	for _, volume := range live.Volumes {
+		if !slices.ContainsFunc(desired.Volumes, func(v corev1.Volume) bool { return v.Name == volume.Name }) {
+			desired.Volumes = append(desired.Volumes, volume)
+		}
+	}
+
+	liveIndex := slices.IndexFunc(live.Containers, func(c corev1.Container) bool { return c.Name == containerName })
+	desiredIndex := slices.IndexFunc(desired.Containers, func(c corev1.Container) bool { return c.Name == containerName })
+	if liveIndex < 0 || desiredIndex < 0 {
+		return
+	}
+	liveContainer, container := live.Containers[liveIndex], &desired.Containers[desiredIndex]
+
+	for _, env := range liveContainer.Env {
+		if !slices.ContainsFunc(container.Env, func(e corev1.EnvVar) bool { return e.Name == env.Name }) {
+			container.Env = append(container.Env, env)
+		}
+	}
+	for _, envFrom := range liveContainer.EnvFrom {
+		if !slices.ContainsFunc(container.EnvFrom, func(e corev1.EnvFromSource) bool { return reflect.DeepEqual(e, envFrom) }) {
+			container.EnvFrom = append(container.EnvFrom, envFrom)
+		}
+	}
+	for _, mount := range liveContainer.VolumeMounts {
+		if !slices.ContainsFunc(container.VolumeMounts, func(m corev1.VolumeMount) bool { return m.MountPath == mount.MountPath }) {
+			container.VolumeMounts = append(container.VolumeMounts, mount)
+		}
+	}
+}
+
+// Degradations returns the optional integrations that were skipped.
+func (o *Options) Degradations() []synchronizer.Degradation {
+	return o.degradations
+}
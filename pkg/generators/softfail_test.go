@@ -0,0 +1,94 @@
+package generators
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nais/naiserator/pkg/naiserator/config"
+	"github.com/nais/naiserator/pkg/resourcecreator/resource"
+	"github.com/nais/naiserator/pkg/synchronizer"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestOptions_optional(t *testing.T) {
+	o := &Options{}
+	o.Config.SoftFailIntegrations = []string{config.IntegrationAiven}
+	ast := resource.NewAst()
+
+	t.Run("soft-failing integration leaves the ast untouched", func(t *testing.T) {
+		err := o.optional(config.IntegrationAiven, ast, func(ast *resource.Ast) error {
+			ast.AppendEnv(corev1.EnvVar{Name: "AIVEN"})
+			return errors.New("no such topic")
+		})
+		assert.NoError(t, err)
+		assert.Empty(t, ast.Env)
+		assert.Equal(t, []synchronizer.Degradation{{Integration: config.IntegrationAiven, Message: "no such topic"}}, o.Degradations())
+	})
+
+	t.Run("other integrations fail", func(t *testing.T) {
+		err := o.optional(config.IntegrationPostgres, ast, func(ast *resource.Ast) error {
+			return errors.New("no such cluster")
+		})
+		assert.EqualError(t, err, "no such cluster")
+	})
+
+	t.Run("successful integration updates the ast", func(t *testing.T) {
+		err := o.optional(config.IntegrationPostgres, ast, func(ast *resource.Ast) error {
+			ast.AppendEnv(corev1.EnvVar{Name: "PGHOST"})
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Len(t, ast.Env, 1)
+	})
+
+	t.Run("workload annotation enables soft-fail", func(t *testing.T) {
+		o := &Options{WorkloadSoftFail: config.IntegrationPostgres}
+		err := o.softFail(config.IntegrationPostgres, errors.New("not ready"))
+		assert.NoError(t, err)
+		assert.True(t, o.degraded(config.IntegrationPostgres))
+	})
+}
+
+func TestOptions_keepLivePodSpec(t *testing.T) {
+	live := &corev1.PodSpec{
+		Containers: []corev1.Container{{
+			Name:         "myapplication",
+			Env:          []corev1.EnvVar{{Name: "PGHOST", Value: "mycluster"}, {Name: "PORT", Value: "8080"}},
+			EnvFrom:      []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "aiven-myapplication"}}}},
+			VolumeMounts: []corev1.VolumeMount{{Name: "aiven-credentials", MountPath: "/var/run/secrets/nais.io/kafka"}},
+		}},
+		Volumes: []corev1.Volume{{Name: "aiven-credentials"}},
+	}
+	generate := func() (*appsv1.Deployment, resource.Operations) {
+		deployment := &appsv1.Deployment{}
+		deployment.Spec.Template.Spec.Containers = []corev1.Container{{
+			Name: "myapplication",
+			Env:  []corev1.EnvVar{{Name: "PORT", Value: "8081"}},
+		}}
+		return deployment, resource.Operations{{Operation: resource.OperationCreateOrUpdate, Resource: deployment}}
+	}
+
+	t.Run("degraded integrations keep the live pod spec", func(t *testing.T) {
+		o := &Options{WorkloadName: "myapplication", livePodSpec: live}
+		o.degradations = []synchronizer.Degradation{{Integration: config.IntegrationAiven, Message: "no such topic"}}
+		deployment, operations := generate()
+		o.keepLivePodSpec(operations)
+
+		container := deployment.Spec.Template.Spec.Containers[0]
+		assert.Equal(t, []corev1.EnvVar{{Name: "PORT", Value: "8081"}, {Name: "PGHOST", Value: "mycluster"}}, container.Env)
+		assert.Equal(t, live.Containers[0].EnvFrom, container.EnvFrom)
+		assert.Equal(t, live.Containers[0].VolumeMounts, container.VolumeMounts)
+		assert.Equal(t, live.Volumes, deployment.Spec.Template.Spec.Volumes)
+	})
+
+	t.Run("healthy integrations generate the pod spec from scratch", func(t *testing.T) {
+		o := &Options{WorkloadName: "myapplication", livePodSpec: live}
+		deployment, operations := generate()
+		o.keepLivePodSpec(operations)
+
+		assert.Len(t, deployment.Spec.Template.Spec.Containers[0].Env, 1)
+		assert.Empty(t, deployment.Spec.Template.Spec.Volumes)
+	})
+}
@@ -0,0 +1,93 @@
+// Package lifecycle publishes workload lifecycle transitions as CloudEvents,
+// so that dashboards and notifications don't have to poll short-lived Kubernetes events.
+package lifecycle
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nais/naiserator/pkg/resourcecreator/resource"
+)
+
+// CloudEvents types published by Naiserator.
+const (
+	TypeSyncStarted      = "io.nais.naiserator.sync.started"
+	TypeResourcesApplied = "io.nais.naiserator.resources.applied"
+	TypeRolloutComplete  = "io.nais.naiserator.rollout.complete"
+	TypeRolloutFailed    = "io.nais.naiserator.rollout.failed"
+	TypeWorkloadDeleted  = "io.nais.naiserator.workload.deleted"
+
+	SpecVersion = "1.0"
+	ContentType = "application/cloudevents+json"
+)
+
+// Event is a CloudEvent in structured content mode.
+// See https://github.com/cloudevents/spec/blob/v1.0.2/cloudevents/spec.md
+type Event struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype"`
+	Data            Data      `json:"data"`
+}
+
+type Data struct {
+	Kind          string            `json:"kind"`
+	Name          string            `json:"name"`
+	Namespace     string            `json:"namespace"`
+	Team          string            `json:"team"`
+	CorrelationID string            `json:"correlationID"`
+	Image         string            `json:"image,omitempty"`
+	Message       string            `json:"message,omitempty"`
+	Resources     []ResourceSummary `json:"resources,omitempty"`
+}
+
+// ResourceSummary identifies a resource generated for the workload, and what is done with it.
+type ResourceSummary struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Operation string `json:"operation"`
+}
+
+type imageSource interface {
+	GetEffectiveImage() string
+}
+
+// NewEvent describes a lifecycle transition of the workload in the given cluster.
+// The generated resources are summarized if operations are given.
+func NewEvent(eventType, clusterName string, source resource.Source, message string, operations resource.Operations) Event {
+	data := Data{
+		Kind:          source.GetObjectKind().GroupVersionKind().Kind,
+		Name:          source.GetName(),
+		Namespace:     source.GetNamespace(),
+		Team:          source.GetNamespace(),
+		CorrelationID: source.CorrelationID(),
+		Message:       message,
+	}
+
+	if image, ok := source.(imageSource); ok {
+		data.Image = image.GetEffectiveImage()
+	}
+
+	for _, operation := range operations {
+		data.Resources = append(data.Resources, ResourceSummary{
+			Kind:      operation.Resource.GetObjectKind().GroupVersionKind().Kind,
+			Name:      operation.Resource.GetName(),
+			Operation: string(operation.Operation),
+		})
+	}
+
+	return Event{
+		SpecVersion:     SpecVersion,
+		ID:              uuid.NewString(),
+		Source:          "/naiserator/" + clusterName,
+		Type:            eventType,
+		Subject:         data.Namespace + "/" + data.Name,
+		Time:            time.Now(),
+		DataContentType: "application/json",
+		Data:            data,
+	}
+}
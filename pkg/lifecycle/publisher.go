@@ -0,0 +1,199 @@
+package lifecycle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nais/naiserator/pkg/naiserator/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	sendTimeout = 10 * time.Second
+
+	// Failed sends are retried with exponential backoff, starting at retryBackoff, until sendAttempts have failed.
+	sendAttempts = 5
+	retryBackoff = 500 * time.Millisecond
+
+	// drainTimeout is how long queued events are delivered for after the publisher has been stopped.
+	drainTimeout = 10 * time.Second
+)
+
+// Sink delivers a single event.
+type Sink interface {
+	Send(ctx context.Context, event Event) error
+}
+
+// HTTPSink posts events to an HTTP endpoint.
+type HTTPSink struct {
+	URL    string
+	Client *http.Client
+}
+
+func (s *HTTPSink) Send(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", ContentType)
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("sink responded with %s", resp.Status)
+	}
+
+	return nil
+}
+
+// SpoolSink writes each event to its own file in a directory, for another process to pick up.
+type SpoolSink struct {
+	Directory string
+}
+
+func (s *SpoolSink) Send(_ context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so that readers never see partially written events.
+	name := fmt.Sprintf("%s-%s.json", event.Time.UTC().Format("20060102T150405.000000000Z"), event.ID)
+	tmp := filepath.Join(s.Directory, "."+name)
+	err = os.WriteFile(tmp, body, 0o644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, filepath.Join(s.Directory, name))
+}
+
+// Publisher queues events and delivers them in the background, so that a slow or unavailable sink
+// never holds up a reconcile. Events are dropped when the queue is full, or when the sink keeps failing.
+type Publisher struct {
+	sink  Sink
+	queue chan Event
+}
+
+func NewPublisher(sink Sink, queueSize int) *Publisher {
+	return &Publisher{
+		sink:  sink,
+		queue: make(chan Event, queueSize),
+	}
+}
+
+// NewPublisherFromConfig returns a publisher for the configured sink, or nil if lifecycle events are disabled.
+func NewPublisherFromConfig(cfg config.LifecycleEvents) *Publisher {
+	switch {
+	case len(cfg.SinkURL) > 0:
+		return NewPublisher(&HTTPSink{URL: cfg.SinkURL, Client: &http.Client{Timeout: sendTimeout}}, cfg.QueueSize)
+	case len(cfg.SpoolDirectory) > 0:
+		return NewPublisher(&SpoolSink{Directory: cfg.SpoolDirectory}, cfg.QueueSize)
+	default:
+		return nil
+	}
+}
+
+// Publish queues the event for delivery. It is safe to call on a nil publisher.
+func (p *Publisher) Publish(event Event) {
+	if p == nil {
+		return
+	}
+
+	select {
+	case p.queue <- event:
+	default:
+		log.Warnf("Lifecycle event queue is full; dropping %s event for %s", event.Type, event.Subject)
+	}
+}
+
+// Start delivers queued events until the context is cancelled,
+// and then delivers the events still in the queue for up to drainTimeout.
+func (p *Publisher) Start(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			p.drain(nil)
+			return nil
+		case event := <-p.queue:
+			err := p.deliver(ctx, event)
+			if err != nil && ctx.Err() != nil {
+				// Interrupted by shutdown; the event is sent again while draining.
+				p.drain(&event)
+				return nil
+			}
+			if err != nil {
+				log.Errorf("Publish %s event for %s: %s", event.Type, event.Subject, err)
+			}
+		}
+	}
+}
+
+// deliver sends the event, retrying failed sends with exponential backoff.
+func (p *Publisher) deliver(ctx context.Context, event Event) error {
+	backoff := retryBackoff
+	for attempt := 1; ; attempt++ {
+		sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+		err := p.sink.Send(sendCtx, event)
+		cancel()
+		if err == nil || attempt == sendAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+			backoff *= 2
+		}
+	}
+}
+
+// drain delivers the given event, if any, and the events left in the queue, until the queue is empty or
+// drainTimeout has passed.
+func (p *Publisher) drain(pending *Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	for {
+		var event Event
+		if pending != nil {
+			event, pending = *pending, nil
+		} else {
+			select {
+			case event = <-p.queue:
+			default:
+				return
+			}
+		}
+
+		err := p.deliver(ctx, event)
+		if err != nil && ctx.Err() != nil {
+			log.Errorf("Shutting down; dropping %s event for %s and %d queued lifecycle events", event.Type, event.Subject, len(p.queue))
+			return
+		}
+		if err != nil {
+			log.Errorf("Publish %s event for %s: %s", event.Type, event.Subject, err)
+		}
+	}
+}
+
+// NeedLeaderElection is false, so that events queued before losing leadership are still delivered.
+func (p *Publisher) NeedLeaderElection() bool {
+	return false
+}
@@ -0,0 +1,146 @@
+package lifecycle_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nais/naiserator/pkg/lifecycle"
+)
+
+func fixture() lifecycle.Event {
+	return lifecycle.Event{
+		SpecVersion:     lifecycle.SpecVersion,
+		ID:              "f3b8a5d2-6a7e-4c4e-9d43-2f4b4c0d6e11",
+		Source:          "/naiserator/test-cluster",
+		Type:            lifecycle.TypeRolloutComplete,
+		Subject:         "myteam/myapp",
+		Time:            time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC),
+		DataContentType: "application/json",
+		Data: lifecycle.Data{
+			Kind:          "Application",
+			Name:          "myapp",
+			Namespace:     "myteam",
+			Team:          "myteam",
+			CorrelationID: "correlation-id",
+			Image:         "europe-north1-docker.pkg.dev/nais/myteam/myapp:1.0",
+		},
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	received := make(chan lifecycle.Event, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, lifecycle.ContentType, r.Header.Get("Content-Type"))
+		event := lifecycle.Event{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		received <- event
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	publisher := lifecycle.NewPublisher(&lifecycle.HTTPSink{URL: server.URL, Client: server.Client()}, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go publisher.Start(ctx)
+
+	publisher.Publish(fixture())
+
+	select {
+	case event := <-received:
+		assert.Equal(t, fixture(), event)
+	case <-time.After(5 * time.Second):
+		t.Fatal("event not delivered")
+	}
+}
+
+func TestPublisher_Retry(t *testing.T) {
+	var attempts atomic.Int32
+	received := make(chan lifecycle.Event, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		event := lifecycle.Event{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		received <- event
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	publisher := lifecycle.NewPublisher(&lifecycle.HTTPSink{URL: server.URL, Client: server.Client()}, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go publisher.Start(ctx)
+
+	publisher.Publish(fixture())
+
+	select {
+	case event := <-received:
+		assert.Equal(t, fixture(), event)
+		assert.Equal(t, int32(3), attempts.Load())
+	case <-time.After(5 * time.Second):
+		t.Fatal("event not delivered")
+	}
+}
+
+func TestPublisher_Drain(t *testing.T) {
+	dir := t.TempDir()
+	publisher := lifecycle.NewPublisher(&lifecycle.SpoolSink{Directory: dir}, 10)
+
+	for i := 0; i < 3; i++ {
+		event := fixture()
+		event.ID = fmt.Sprintf("event-%d", i)
+		publisher.Publish(event)
+	}
+
+	// Events queued when the publisher is stopped are still delivered.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.NoError(t, publisher.Start(ctx))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 3)
+}
+
+func TestHTTPSink_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	sink := &lifecycle.HTTPSink{URL: server.URL, Client: server.Client()}
+	assert.ErrorContains(t, sink.Send(context.Background(), fixture()), "sink responded with 503")
+}
+
+func TestSpoolSink(t *testing.T) {
+	dir := t.TempDir()
+	sink := &lifecycle.SpoolSink{Directory: dir}
+	assert.NoError(t, sink.Send(context.Background(), fixture()))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	data, err := os.ReadFile(files[0])
+	assert.NoError(t, err)
+	event := lifecycle.Event{}
+	assert.NoError(t, json.Unmarshal(data, &event))
+	assert.Equal(t, fixture(), event)
+}
+
+func TestPublisher_Nil(t *testing.T) {
+	var publisher *lifecycle.Publisher
+	publisher.Publish(fixture())
+}
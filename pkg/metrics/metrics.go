@@ -5,6 +5,12 @@ import (
 )
 
 var (
+	DeprecatedInputs = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name:      "deprecated_inputs",
+		Namespace: "naiserator",
+		Help:      "workloads using deprecated inputs, with the date support will be removed",
+	}, []string{"kind", "team", "name", "deprecation", "sunset"})
+
 	HttpRequests = prometheus.NewCounter(prometheus.CounterOpts{
 		Name:      "http_requests",
 		Namespace: "naiserator",
@@ -44,5 +50,6 @@ func Register(registry prometheus.Registerer) {
 		ResourcesGenerated,
 		HttpRequests,
 		KubernetesResourceWriteDuration,
+		DeprecatedInputs,
 	)
 }
@@ -26,6 +26,16 @@ type Informer struct {
 	FullSyncInterval time.Duration `json:"full-sync-interval"`
 }
 
+// CapabilityCheck decides what happens when an enabled feature needs API groups that the cluster doesn't serve.
+type CapabilityCheck string
+
+const (
+	CapabilityCheckOff     CapabilityCheck = "off"
+	CapabilityCheckWarn    CapabilityCheck = "warn"
+	CapabilityCheckFail    CapabilityCheck = "fail"
+	CapabilityCheckDisable CapabilityCheck = "disable"
+)
+
 type Synchronizer struct {
 	SynchronizationTimeout time.Duration `json:"synchronization-timeout"`
 	RolloutTimeout         time.Duration `json:"rollout-timeout"`
@@ -37,6 +47,7 @@ type Features struct {
 	AccessPolicyNotAllowedCIDRs []string `json:"access-policy-not-allowed-cidrs"`
 	Azurerator                  bool     `json:"azurerator"`
 	CNRM                        bool     `json:"cnrm"`
+	DeletionProtection          bool     `json:"deletion-protection"`
 	GARToleration               bool     `json:"gar-toleration"`
 	GCP                         bool     `json:"gcp"`
 	HAProxy                     bool     `json:"haproxy"`
@@ -82,7 +93,8 @@ type OtelCollector struct {
 }
 
 type Logging struct {
-	Destinations []string `json:"destinations"`
+	DefaultDestination string   `json:"default-destination"`
+	Destinations       []string `json:"destinations"`
 }
 
 type GatewayMapping struct {
@@ -120,6 +132,25 @@ type LeaderElection struct {
 	Image string `json:"image"`
 }
 
+// ControllerLeaderElection configures leader election between Naiserator replicas,
+// as opposed to LeaderElection which configures the sidecar offered to applications.
+type ControllerLeaderElection struct {
+	Enabled       bool          `json:"enabled"`
+	LeaseName     string        `json:"lease-name"`
+	Namespace     string        `json:"namespace"`
+	LeaseDuration time.Duration `json:"lease-duration"`
+	RenewDeadline time.Duration `json:"renew-deadline"`
+	RetryPeriod   time.Duration `json:"retry-period"`
+}
+
+// LifecycleEvents configures where workload lifecycle transitions are published as CloudEvents.
+// Events are posted to the sink URL, or written as files to the spool directory.
+type LifecycleEvents struct {
+	SinkURL        string `json:"sink-url"`
+	SpoolDirectory string `json:"spool-directory"`
+	QueueSize      int    `json:"queue-size"`
+}
+
 type FQDNPolicy struct {
 	Enabled bool `json:"enabled"`
 }
@@ -133,37 +164,89 @@ type Frontend struct {
 	TelemetryURL string `json:"telemetry-url"`
 }
 
+// ProgressiveDelivery configures canary and blue-green rollouts of Applications.
+// Steps are the percentages of ingress traffic sent to the canary, in order, unless the workload sets its own.
+// The Prometheus URL is used for the analysis queries supplied by teams.
+type ProgressiveDelivery struct {
+	PrometheusURL string        `json:"prometheus-url"`
+	Steps         []int         `json:"steps"`
+	StepInterval  time.Duration `json:"step-interval"`
+}
+
+// Maintenance configures how Applications in maintenance mode are served.
+// Backend is the DNS name of a shared service that serves the maintenance page over HTTP on port 80.
+// Without a backend, the ingress controller serves the maintenance message itself.
+type Maintenance struct {
+	Backend string `json:"backend"`
+}
+
+// Preview configures ephemeral preview environments, such as the deployments of pull requests.
+// Previews are deleted DefaultTTL after their last deployment, unless the workload sets its own TTL,
+// which can't be longer than MaxTTL.
+type Preview struct {
+	DefaultTTL time.Duration `json:"default-ttl"`
+	MaxTTL     time.Duration `json:"max-ttl"`
+}
+
+// PolicyRule is a guardrail that platform operators can enforce on Applications and Naisjobs.
+// Each rule applies to all namespaces, or to the listed namespaces only.
+// All constraints set on the rule are checked; constraints left empty are ignored.
+type PolicyRule struct {
+	Name                        string   `json:"name"`
+	Severity                    string   `json:"severity"`
+	Namespaces                  []string `json:"namespaces"`
+	AllowedImageRegistries      []string `json:"allowed-image-registries"`
+	ForbiddenRunAsUser          []string `json:"forbidden-run-as-user"`
+	MaxMemory                   string   `json:"max-memory"`
+	MaxReplicas                 int      `json:"max-replicas"`
+	RequireResourceRequests     bool     `json:"require-resource-requests"`
+	RequiredLoggingDestinations []string `json:"required-logging-destinations"`
+}
+
 type Config struct {
-	AivenGeneration                   int              `json:"aiven-generation"`
-	AivenProject                      string           `json:"aiven-project"`
-	AivenRange                        string           `json:"aiven-range"`
-	APIServerIP                       string           `json:"api-server-ip"`
-	Bind                              string           `json:"bind"`
-	ClusterName                       string           `json:"cluster-name"`
-	DocURL                            string           `json:"doc-url"`
-	DryRun                            bool             `json:"dry-run"`
-	FQDNPolicy                        FQDNPolicy       `json:"fqdn-policy"`
-	Features                          Features         `json:"features"`
-	Frontend                          Frontend         `json:"frontend"`
-	DomainIngressClassMapping         []GatewayMapping `json:"domain-ingressclass-mapping"`
-	GoogleCloudSQLProxyContainerImage string           `json:"google-cloud-sql-proxy-container-image"`
-	GoogleProjectID                   string           `json:"google-project-id"`
-	HealthProbeBindAddress            string           `json:"health-probe-bind-address"`
-	HostAliases                       []HostAlias      `json:"host-aliases"`
-	ImagePullSecrets                  []string         `json:"image-pull-secrets"`
-	Informer                          Informer         `json:"informer"`
-	Kubeconfig                        string           `json:"kubeconfig"`
-	LeaderElection                    LeaderElection   `json:"leader-election"`
-	Log                               Log              `json:"log"`
-	MaxConcurrentReconciles           int              `json:"max-concurrent-reconciles"`
-	NaisNamespace                     string           `json:"nais-namespace"`
-	Observability                     Observability    `json:"observability"`
-	Proxy                             Proxy            `json:"proxy"`
-	Ratelimit                         Ratelimit        `json:"ratelimit"`
-	Synchronizer                      Synchronizer     `json:"synchronizer"`
-	Texas                             Texas            `json:"texas"`
-	Vault                             Vault            `json:"vault"`
-	Wonderwall                        Wonderwall       `json:"wonderwall"`
+	AivenGeneration                   int                      `json:"aiven-generation"`
+	AivenProject                      string                   `json:"aiven-project"`
+	AivenRange                        string                   `json:"aiven-range"`
+	APIServerIP                       string                   `json:"api-server-ip"`
+	Bind                              string                   `json:"bind"`
+	CapabilityCheck                   CapabilityCheck          `json:"capability-check"`
+	ClusterName                       string                   `json:"cluster-name"`
+	ControllerLeaderElection          ControllerLeaderElection `json:"controller-leader-election"`
+	DocURL                            string                   `json:"doc-url"`
+	DryRun                            bool                     `json:"dry-run"`
+	FQDNPolicy                        FQDNPolicy               `json:"fqdn-policy"`
+	FeatureGates                      []FeatureGate            `json:"feature-gates"`
+	Features                          Features                 `json:"features"`
+	FreezeWindows                     []FreezeWindow           `json:"freeze-windows"`
+	Frontend                          Frontend                 `json:"frontend"`
+	DomainIngressClassMapping         []GatewayMapping         `json:"domain-ingressclass-mapping"`
+	GoogleCloudSQLProxyContainerImage string                   `json:"google-cloud-sql-proxy-container-image"`
+	GoogleProjectID                   string                   `json:"google-project-id"`
+	HealthProbeBindAddress            string                   `json:"health-probe-bind-address"`
+	HostAliases                       []HostAlias              `json:"host-aliases"`
+	ImagePullSecrets                  []string                 `json:"image-pull-secrets"`
+	Informer                          Informer                 `json:"informer"`
+	Kubeconfig                        string                   `json:"kubeconfig"`
+	LeaderElection                    LeaderElection           `json:"leader-election"`
+	LifecycleEvents                   LifecycleEvents          `json:"lifecycle-events"`
+	Log                               Log                      `json:"log"`
+	Maintenance                       Maintenance              `json:"maintenance"`
+	MaxConcurrentReconciles           int                      `json:"max-concurrent-reconciles"`
+	NaisNamespace                     string                   `json:"nais-namespace"`
+	Observability                     Observability            `json:"observability"`
+	PolicyRules                       []PolicyRule             `json:"policy-rules"`
+	Preview                           Preview                  `json:"preview"`
+	ProgressiveDelivery               ProgressiveDelivery      `json:"progressive-delivery"`
+	Proxy                             Proxy                    `json:"proxy"`
+	Ratelimit                         Ratelimit                `json:"ratelimit"`
+	RecordDirectory                   string                   `json:"record-directory"`
+	Shard                             Shard                    `json:"shard"`
+	SidecarChannels                   []SidecarChannel         `json:"sidecar-channels"`
+	SoftFailIntegrations              []string                 `json:"soft-fail-integrations"`
+	Synchronizer                      Synchronizer             `json:"synchronizer"`
+	Texas                             Texas                    `json:"texas"`
+	Vault                             Vault                    `json:"vault"`
+	Wonderwall                        Wonderwall               `json:"wonderwall"`
 }
 
 const (
@@ -172,8 +255,15 @@ const (
 	AivenRange                                    = "aiven-range"
 	APIServerIP                                   = "api-server-ip"
 	Bind                                          = "bind"
+	CapabilityCheckMode                           = "capability-check"
 	HealthProbeBindAddress                        = "health-probe-bind-address"
 	ClusterName                                   = "cluster-name"
+	ControllerLeaderElectionEnabled               = "controller-leader-election.enabled"
+	ControllerLeaderElectionLeaseName             = "controller-leader-election.lease-name"
+	ControllerLeaderElectionNamespace             = "controller-leader-election.namespace"
+	ControllerLeaderElectionLeaseDuration         = "controller-leader-election.lease-duration"
+	ControllerLeaderElectionRenewDeadline         = "controller-leader-election.renew-deadline"
+	ControllerLeaderElectionRetryPeriod           = "controller-leader-election.retry-period"
 	DryRun                                        = "dry-run"
 	NaisNamespace                                 = "nais-namespace"
 	FeaturesAccessPolicyNotAllowedCIDRs           = "features.access-policy-not-allowed-cidrs"
@@ -183,6 +273,7 @@ const (
 	FeaturesIDPorten                              = "features.idporten"
 	FeaturesJwker                                 = "features.jwker"
 	FeaturesCNRM                                  = "features.cnrm"
+	FeaturesDeletionProtection                    = "features.deletion-protection"
 	FeaturesKafkarator                            = "features.kafkarator"
 	FeaturesMaskinporten                          = "features.maskinporten"
 	FeaturesNetworkPolicy                         = "features.network-policy"
@@ -199,7 +290,12 @@ const (
 	InformerFullSynchronizationInterval           = "informer.full-sync-interval"
 	KubeConfig                                    = "kubeconfig"
 	LeaderElectionImage                           = "leader-election.image"
+	LifecycleEventsQueueSize                      = "lifecycle-events.queue-size"
+	LifecycleEventsSinkURL                        = "lifecycle-events.sink-url"
+	LifecycleEventsSpoolDirectory                 = "lifecycle-events.spool-directory"
+	MaintenanceBackend                            = "maintenance.backend"
 	MaxConcurrentReconciles                       = "max-concurrent-reconciles"
+	ObservabilityLoggingDefaultDestination        = "observability.logging.default-destination"
 	ObservabilityLoggingDestinations              = "observability.logging.destinations"
 	ObservabilityOtelCollectorLabels              = "observability.otel.collector.labels"
 	ObservabilityOtelCollectorNamespace           = "observability.otel.collector.namespace"
@@ -211,10 +307,21 @@ const (
 	ObservabilityOtelDestinations                 = "observability.otel.destinations"
 	ObservabilityOtelAutoInstrumentationAppConfig = "observability.otel.auto-instrumentation.app-config"
 	ObservabilityOtelAutoInstrumentationEnabled   = "observability.otel.auto-instrumentation.enabled"
+	PreviewDefaultTTL                             = "preview.default-ttl"
+	PreviewMaxTTL                                 = "preview.max-ttl"
+	ProgressiveDeliveryPrometheusURL              = "progressive-delivery.prometheus-url"
+	ProgressiveDeliveryStepInterval               = "progressive-delivery.step-interval"
+	ProgressiveDeliverySteps                      = "progressive-delivery.steps"
 	ProxyAddress                                  = "proxy.address"
 	ProxyExclude                                  = "proxy.exclude"
 	RateLimitBurst                                = "ratelimit.burst"
 	RateLimitQPS                                  = "ratelimit.qps"
+	RecordDirectory                               = "record-directory"
+	ShardCount                                    = "shard.count"
+	ShardIndex                                    = "shard.index"
+	ShardLabelSelector                            = "shard.label-selector"
+	ShardNamespaces                               = "shard.namespaces"
+	SoftFailIntegrations                          = "soft-fail-integrations"
 	SynchronizerRolloutCheckInterval              = "synchronizer.rollout-check-interval"
 	SynchronizerRolloutTimeout                    = "synchronizer.rollout-timeout"
 	SynchronizerSynchronizationTimeout            = "synchronizer.synchronization-timeout"
@@ -233,17 +340,19 @@ func init() {
 	viper.AutomaticEnv()
 	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_", ".", "_"))
 
-	// Read configuration file from working directory and/or /etc.
+	// Read configuration file from working directory, /etc and/or /etc/naiserator.
 	// File formats supported include JSON, TOML, YAML, HCL, envfile and Java properties config files
 	viper.SetConfigName("naiserator")
 	viper.AddConfigPath(".")
 	viper.AddConfigPath("/etc")
+	viper.AddConfigPath("/etc/naiserator")
 
 	// Provide command-line flags
 	flag.Bool(DryRun, false, "set to true to run without any actual changes to the cluster")
 	flag.String(KubeConfig, "", "path to Kubernetes config file")
 	flag.String(Bind, "127.0.0.1:8080", "ip:port where http requests are served")
 	flag.String(HealthProbeBindAddress, "127.0.0.1:8085", "ip:port where health probes are performed")
+	flag.String(CapabilityCheckMode, string(CapabilityCheckWarn), "what to do when enabled features need API groups the cluster doesn't serve: off, warn, fail or disable")
 	flag.String(ClusterName, "cluster-name-unconfigured", "cluster name as presented to deployed applications")
 	flag.Int(AivenGeneration, 0, "the generation of aiven secrets in this cluster")
 	flag.String(AivenProject, "aiven-project", "main Aiven project for this cluster")
@@ -262,6 +371,7 @@ func init() {
 	flag.Bool(FeaturesHAProxy, false, "enable creating dual ingress, supporting Nginx and HAProxy")
 	flag.Bool(FeaturesJwker, false, "enable creation of Jwker resources and secret injection")
 	flag.Bool(FeaturesCNRM, false, "enable creation of CNRM resources")
+	flag.Bool(FeaturesDeletionProtection, false, "hold deletion of workloads owning stateful resources until confirmed")
 	flag.Bool(FeaturesAzurerator, false, "enable creation of AzureAdApplication resources and secret injection")
 	flag.Bool(FeaturesKafkarator, false, "enable Kafkarator secret injection")
 	flag.Bool(FeaturesIDPorten, false, "enable creation of IDPorten client resources and secret injection")
@@ -277,8 +387,19 @@ func init() {
 		"how often to run a full synchronization of all applications",
 	)
 
+	flag.Bool(ControllerLeaderElectionEnabled, false, "elect a single active Naiserator replica using a coordination lease")
+	flag.String(ControllerLeaderElectionLeaseName, "naiserator", "name of the lease used for electing the active Naiserator replica")
+	flag.String(ControllerLeaderElectionNamespace, "", "namespace of the leader election lease; defaults to the namespace Naiserator runs in")
+	flag.Duration(ControllerLeaderElectionLeaseDuration, 15*time.Second, "how long standby replicas wait before taking over a lease that isn't renewed")
+	flag.Duration(ControllerLeaderElectionRenewDeadline, 10*time.Second, "how long the active replica keeps trying to renew the lease before giving up leadership")
+	flag.Duration(ControllerLeaderElectionRetryPeriod, 2*time.Second, "how often replicas try to acquire or renew the lease")
+
 	flag.String(LeaderElectionImage, "", "image to use for leader election in deployed applications")
+	flag.String(LifecycleEventsSinkURL, "", "HTTP endpoint that workload lifecycle events are posted to as CloudEvents")
+	flag.String(LifecycleEventsSpoolDirectory, "", "directory that workload lifecycle events are written to as CloudEvents, if no sink URL is set")
+	flag.Int(LifecycleEventsQueueSize, 1000, "number of lifecycle events buffered for publishing; events are dropped when the queue is full")
 	flag.Int(MaxConcurrentReconciles, 1, "maximum number of concurrent Reconciles which can be run by the controller.")
+	flag.String(ObservabilityLoggingDefaultDestination, "", "logging destination used when workloads don't specify any; empty means the collector default")
 	flag.StringArray(ObservabilityLoggingDestinations, []string{}, "list of valid logging destinations")
 	flag.Bool(ObservabilityOtelEnabled, false, "enable OpenTelemetry")
 	flag.StringArray(ObservabilityOtelDestinations, []string{}, "list of valid otel storage destinations")
@@ -292,6 +413,12 @@ func init() {
 	flag.StringArray(ObservabilityOtelCollectorLabels, []string{}, "list of labels to be used by the OpenTelemetry collector")
 	flag.Int(RateLimitQPS, 20, "how quickly the rate limit burst bucket is filled per second")
 	flag.Int(RateLimitBurst, 200, "how many requests to Kubernetes to allow per second")
+	flag.Int(ShardCount, 0, "number of shards that workloads are distributed between by namespace; 0 disables sharding by hash")
+	flag.Int(ShardIndex, 0, "the shard handled by this instance, from 0 to shard count - 1")
+	flag.String(ShardLabelSelector, "", "only handle workloads matching this label selector")
+	flag.StringSlice(ShardNamespaces, []string{}, "only handle workloads in these namespaces")
+	flag.StringSlice(SoftFailIntegrations, []string{}, "optional integrations that degrade workloads instead of failing rollouts: aiven, fqdn-policy, postgres, prometheus")
+	flag.String(RecordDirectory, "", "write replay bundles for workloads annotated with naiserator.nais.io/record to this directory; empty disables recording")
 
 	flag.Duration(
 		SynchronizerSynchronizationTimeout, time.Duration(5*time.Second),
@@ -306,6 +433,15 @@ func init() {
 		"how long to keep checking for a successful deployment rollout",
 	)
 
+	flag.String(MaintenanceBackend, "", "DNS name of the service that serves the maintenance page of applications in maintenance mode, over HTTP on port 80")
+
+	flag.Duration(PreviewDefaultTTL, 72*time.Hour, "how long preview environments live after their last deployment, for workloads that don't set their own")
+	flag.Duration(PreviewMaxTTL, 14*24*time.Hour, "longest time to live that preview environments can set")
+
+	flag.String(ProgressiveDeliveryPrometheusURL, "", "Prometheus server used for the analysis queries of canary rollouts")
+	flag.IntSlice(ProgressiveDeliverySteps, []int{10, 50}, "percentages of ingress traffic sent to the canary, in order, for workloads that don't set their own")
+	flag.Duration(ProgressiveDeliveryStepInterval, 5*time.Minute, "how long each step of a canary or blue-green rollout lasts, for workloads that don't set their own")
+
 	flag.String(TexasImage, "", "Docker image used for Texas")
 
 	flag.String(ProxyAddress, "", "HTTPS?_PROXY environment variable injected into containers")
@@ -340,6 +476,11 @@ func Print(redacted []string) {
 func decoderHook(dc *mapstructure.DecoderConfig) {
 	dc.TagName = "json"
 	dc.ErrorUnused = true
+	hooks := []mapstructure.DecodeHookFunc{mapstructure.StringToTimeHookFunc(time.RFC3339)}
+	if dc.DecodeHook != nil {
+		hooks = append([]mapstructure.DecodeHookFunc{dc.DecodeHook}, hooks...)
+	}
+	dc.DecodeHook = mapstructure.ComposeDecodeHookFunc(hooks...)
 }
 
 func New() (*Config, error) {
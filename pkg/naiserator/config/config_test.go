@@ -1 +1,347 @@
 package config_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nais/naiserator/pkg/naiserator/config"
+)
+
+func validConfig() config.Config {
+	return config.Config{
+		CapabilityCheck: config.CapabilityCheckWarn,
+		DomainIngressClassMapping: []config.GatewayMapping{
+			{DomainSuffix: ".nais.io", IngressClass: "nais-ingress"},
+		},
+		LeaderElection:          config.LeaderElection{Image: "elector:1"},
+		MaxConcurrentReconciles: 1,
+		NaisNamespace:           "nais-system",
+		Synchronizer: config.Synchronizer{
+			SynchronizationTimeout: time.Minute,
+			RolloutTimeout:         time.Minute,
+			RolloutCheckInterval:   time.Second,
+		},
+		Features: config.Features{
+			NetworkPolicy: true,
+			Texas:         true,
+		},
+		Texas: config.Texas{Image: "texas:1"},
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, validConfig().Validate())
+
+	cfg := validConfig()
+	cfg.DomainIngressClassMapping = nil
+	cfg.Features.Texas = true
+	cfg.Texas.Image = ""
+	cfg.Features.IDPorten = true
+	cfg.Features.Wonderwall = true
+	cfg.Features.Vault = true
+	cfg.Observability.Otel.Enabled = true
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "no gateway mappings defined")
+	assert.ErrorContains(t, err, "texas is enabled, but texas image not specified")
+	assert.ErrorContains(t, err, "wonderwall is enabled, but wonderwall image not specified")
+	assert.ErrorContains(t, err, "vault address not found in environment")
+	assert.ErrorContains(t, err, "otel is enabled, but collector service and namespace not specified")
+	assert.NotContains(t, err.Error(), "idporten requires wonderwall")
+
+	// Only workloads using leader election need the image.
+	cfg = validConfig()
+	cfg.LeaderElection.Image = ""
+	assert.NoError(t, cfg.Validate())
+	assert.Len(t, cfg.Warnings(), 1)
+	assert.Empty(t, validConfig().Warnings())
+
+	cfg = validConfig()
+	cfg.Observability.Otel.AutoInstrumentation.Enabled = true
+	err = cfg.Validate()
+	assert.ErrorContains(t, err, "otel auto-instrumentation requires otel to be enabled")
+	assert.ErrorContains(t, err, "otel auto-instrumentation is enabled, but app config not specified")
+}
+
+func TestLive_Reload(t *testing.T) {
+	live := config.NewLive(validConfig())
+
+	next := validConfig()
+	next.Texas.Image = "texas:2"
+	next.Proxy.Address = "http://proxy:8080"
+	next.MaxConcurrentReconciles = 10
+	next.Features.Vault = true
+
+	changed, err := live.Reload(next)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{config.ProxyAddress, config.TexasImage}, changed)
+	assert.Equal(t, "texas:2", live.Get().Texas.Image)
+	assert.Equal(t, "http://proxy:8080", live.Get().Proxy.Address)
+	// Settings outside the reloadable subset require a restart.
+	assert.Equal(t, 1, live.Get().MaxConcurrentReconciles)
+	assert.False(t, live.Get().Features.Vault)
+
+	changed, err = live.Reload(next)
+	assert.NoError(t, err)
+	assert.Empty(t, changed)
+
+	// Invalid configuration is not applied.
+	next.Texas.Image = ""
+	_, err = live.Reload(next)
+	assert.Error(t, err)
+	assert.Equal(t, "texas:2", live.Get().Texas.Image)
+}
+
+func TestFeatureGate_Enabled(t *testing.T) {
+	cfg := validConfig()
+	cfg.FeatureGates = []config.FeatureGate{
+		{Name: "on", Mode: config.GateOn},
+		{Name: "allowlist", Mode: config.GateAllowlist, Namespaces: []string{"volunteers"}},
+		{Name: "half", Mode: config.GatePercentage, Percentage: 50},
+		{Name: "none", Mode: config.GatePercentage, Percentage: 0},
+		{Name: "all", Mode: config.GatePercentage, Percentage: 100},
+	}
+	assert.NoError(t, cfg.Validate())
+
+	assert.True(t, cfg.FeatureGate("on").Enabled("team", "app"))
+	assert.False(t, cfg.FeatureGate("unknown").Enabled("team", "app"))
+	assert.True(t, cfg.FeatureGate("allowlist").Enabled("volunteers", "app"))
+	assert.False(t, cfg.FeatureGate("allowlist").Enabled("team", "app"))
+	assert.False(t, cfg.FeatureGate("none").Enabled("team", "app"))
+	assert.True(t, cfg.FeatureGate("all").Enabled("team", "app"))
+
+	enabled := 0
+	for i := range 1000 {
+		name := fmt.Sprintf("app-%d", i)
+		first := cfg.FeatureGate("half").Enabled("team", name)
+		assert.Equal(t, first, cfg.FeatureGate("half").Enabled("team", name), "percentage gates must be deterministic")
+		if first {
+			enabled++
+		}
+	}
+	assert.InDelta(t, 500, enabled, 75)
+
+	cfg.FeatureGates = []config.FeatureGate{
+		{Name: "dup", Mode: config.GateOn},
+		{Name: "dup", Mode: config.GateOff},
+		{Name: "empty", Mode: config.GateAllowlist},
+		{Name: "big", Mode: config.GatePercentage, Percentage: 101},
+		{Name: "bad", Mode: "sometimes"},
+	}
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, `feature gate "dup" is defined more than once`)
+	assert.ErrorContains(t, err, `feature gate "empty": allowlist mode requires namespaces`)
+	assert.ErrorContains(t, err, `feature gate "big": percentage must be between 0 and 100`)
+	assert.ErrorContains(t, err, `feature gate "bad": invalid mode "sometimes"`)
+}
+
+func TestConfig_ActiveFreezeWindow(t *testing.T) {
+	cfg := validConfig()
+	cfg.FreezeWindows = []config.FreezeWindow{
+		{
+			Name:  "christmas",
+			Start: time.Date(2026, 12, 20, 0, 0, 0, 0, time.UTC),
+			End:   time.Date(2027, 1, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			Name:       "release",
+			Start:      time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC),
+			End:        time.Date(2027, 1, 10, 0, 0, 0, 0, time.UTC),
+			Namespaces: []string{"payments"},
+		},
+	}
+	assert.NoError(t, cfg.Validate())
+
+	_, found := cfg.ActiveFreezeWindow("team", time.Date(2026, 12, 19, 23, 59, 0, 0, time.UTC))
+	assert.False(t, found)
+
+	window, found := cfg.ActiveFreezeWindow("team", time.Date(2026, 12, 24, 0, 0, 0, 0, time.UTC))
+	assert.True(t, found)
+	assert.Equal(t, "christmas", window.Name)
+
+	window, found = cfg.ActiveFreezeWindow("payments", time.Date(2026, 12, 24, 0, 0, 0, 0, time.UTC))
+	assert.True(t, found)
+	assert.Equal(t, "release", window.Name, "the window ending last is reported")
+
+	_, found = cfg.ActiveFreezeWindow("team", time.Date(2027, 1, 2, 0, 0, 0, 0, time.UTC))
+	assert.False(t, found, "windows end exclusively")
+
+	cfg.FreezeWindows = []config.FreezeWindow{
+		{Name: "backwards", Start: time.Date(2027, 1, 2, 0, 0, 0, 0, time.UTC), End: time.Date(2026, 12, 20, 0, 0, 0, 0, time.UTC)},
+		{Name: "open-ended", Start: time.Date(2027, 1, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, `freeze window "backwards" must end after it starts`)
+	assert.ErrorContains(t, err, `freeze window "open-ended" must have both start and end`)
+}
+
+func TestControllerLeaderElection_Validate(t *testing.T) {
+	cfg := validConfig()
+	cfg.ControllerLeaderElection = config.ControllerLeaderElection{
+		Enabled:       true,
+		LeaseName:     "naiserator",
+		LeaseDuration: 15 * time.Second,
+		RenewDeadline: 10 * time.Second,
+		RetryPeriod:   2 * time.Second,
+	}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.ControllerLeaderElection.RenewDeadline = 20 * time.Second
+	assert.ErrorContains(t, cfg.Validate(), "leader election requires lease duration > renew deadline > retry period > 0")
+
+	cfg.ControllerLeaderElection.Enabled = false
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestLifecycleEvents_Validate(t *testing.T) {
+	cfg := validConfig()
+	cfg.LifecycleEvents = config.LifecycleEvents{
+		SinkURL:   "https://events.example.com/naiserator",
+		QueueSize: 1000,
+	}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.LifecycleEvents.SpoolDirectory = "/var/spool/naiserator"
+	assert.ErrorContains(t, cfg.Validate(), "either a sink url or a spool directory, not both")
+
+	cfg.LifecycleEvents.SinkURL = "events.example.com"
+	assert.ErrorContains(t, cfg.Validate(), "lifecycle events sink url must be an http or https url")
+}
+
+func TestProgressiveDelivery_Validate(t *testing.T) {
+	cfg := validConfig()
+	cfg.ProgressiveDelivery = config.ProgressiveDelivery{
+		PrometheusURL: "http://prometheus.nais-system:9090",
+		Steps:         []int{10, 50},
+		StepInterval:  5 * time.Minute,
+	}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.ProgressiveDelivery.Steps = []int{50, 10}
+	assert.ErrorContains(t, cfg.Validate(), "progressive delivery steps must be increasing percentages between 1 and 99")
+
+	cfg.ProgressiveDelivery.Steps = []int{10, 100}
+	assert.ErrorContains(t, cfg.Validate(), "progressive delivery steps must be increasing percentages between 1 and 99")
+
+	cfg.ProgressiveDelivery.PrometheusURL = "prometheus:9090"
+	assert.ErrorContains(t, cfg.Validate(), "progressive delivery prometheus url must be an http or https url")
+}
+
+func TestMaintenance_Validate(t *testing.T) {
+	cfg := validConfig()
+	cfg.Maintenance.Backend = "maintenance.nais-system.svc.cluster.local"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Maintenance.Backend = "http://maintenance.nais-system"
+	assert.ErrorContains(t, cfg.Validate(), "maintenance backend must be a DNS name")
+}
+
+func TestPreview_Validate(t *testing.T) {
+	cfg := validConfig()
+	cfg.Preview = config.Preview{
+		DefaultTTL: 72 * time.Hour,
+		MaxTTL:     14 * 24 * time.Hour,
+	}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Preview.DefaultTTL = 30 * 24 * time.Hour
+	assert.ErrorContains(t, cfg.Validate(), "preview default ttl must not be longer than max ttl")
+
+	cfg.Preview.MaxTTL = -time.Hour
+	assert.ErrorContains(t, cfg.Validate(), "preview default ttl and max ttl must not be negative")
+}
+
+func TestShard(t *testing.T) {
+	assert.False(t, config.Shard{}.Enabled())
+	assert.True(t, config.Shard{}.OwnsNamespace("team"))
+
+	shard := config.Shard{Namespaces: []string{"team-a", "team-b"}}
+	assert.True(t, shard.OwnsNamespace("team-a"))
+	assert.False(t, shard.OwnsNamespace("team-c"))
+
+	shard.LabelSelector = "tier=gold"
+	assert.True(t, shard.Owns("team-a", map[string]string{"tier": "gold"}))
+	assert.False(t, shard.Owns("team-a", map[string]string{"tier": "silver"}))
+	assert.False(t, shard.Owns("team-c", map[string]string{"tier": "gold"}))
+
+	// Every namespace belongs to exactly one shard, and few namespaces move when a shard is added.
+	owners := make(map[string]int)
+	counts := make([]int, 3)
+	for i := range 3000 {
+		namespace := fmt.Sprintf("team-%d", i)
+		for index := range 3 {
+			if (config.Shard{Index: index, Count: 3}).OwnsNamespace(namespace) {
+				owners[namespace] = index
+				counts[index]++
+			}
+		}
+	}
+	assert.Len(t, owners, 3000)
+	for _, count := range counts {
+		assert.InDelta(t, 1000, count, 150)
+	}
+
+	moved := 0
+	for namespace, index := range owners {
+		if !(config.Shard{Index: index, Count: 4}).OwnsNamespace(namespace) {
+			moved++
+		}
+	}
+	assert.InDelta(t, 750, moved, 150)
+
+	cfg := validConfig()
+	cfg.Shard = config.Shard{Index: 3, Count: 3, LabelSelector: "team in (a"}
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "shard index must be between 0 and shard count - 1")
+	assert.ErrorContains(t, err, "shard label selector")
+}
+
+func TestConfig_SoftFail(t *testing.T) {
+	cfg := validConfig()
+	cfg.SoftFailIntegrations = []string{config.IntegrationFQDNPolicy}
+	assert.NoError(t, cfg.Validate())
+
+	assert.True(t, cfg.SoftFail(config.IntegrationFQDNPolicy, ""))
+	assert.False(t, cfg.SoftFail(config.IntegrationAiven, ""))
+	assert.True(t, cfg.SoftFail(config.IntegrationAiven, "postgres, aiven"))
+	assert.True(t, cfg.SoftFail(config.IntegrationPostgres, "*"))
+	assert.False(t, cfg.SoftFail("deployment", "*"))
+
+	cfg.SoftFailIntegrations = []string{"deployment"}
+	assert.ErrorContains(t, cfg.Validate(), `soft-fail integration "deployment" must be one of aiven, fqdn-policy, postgres, prometheus`)
+}
+
+func TestConfig_WithSidecarChannel(t *testing.T) {
+	cfg := validConfig()
+	cfg.Texas.Image = "texas:1"
+	cfg.Wonderwall.Image = "wonderwall:1"
+	cfg.SidecarChannels = []config.SidecarChannel{
+		{Name: "canary", Images: map[string]string{config.SidecarWonderwall: "wonderwall:2"}},
+	}
+	assert.NoError(t, cfg.Validate())
+
+	stable, err := cfg.WithSidecarChannel(config.DefaultSidecarChannel)
+	assert.NoError(t, err)
+	assert.Equal(t, "wonderwall:1", stable.SidecarImages()[config.SidecarWonderwall])
+
+	canary, err := cfg.WithSidecarChannel("canary")
+	assert.NoError(t, err)
+	assert.Equal(t, "wonderwall:2", canary.SidecarImages()[config.SidecarWonderwall])
+	assert.Equal(t, "texas:1", canary.SidecarImages()[config.SidecarTexas])
+	assert.Equal(t, "wonderwall:1", cfg.Wonderwall.Image)
+
+	_, err = cfg.WithSidecarChannel("beta")
+	assert.EqualError(t, err, `sidecar channel "beta" does not exist in cluster`)
+
+	cfg.SidecarChannels = append(cfg.SidecarChannels,
+		config.SidecarChannel{Name: "canary"},
+		config.SidecarChannel{Name: config.DefaultSidecarChannel},
+		config.SidecarChannel{Name: "beta", Images: map[string]string{"frontend": "frontend:2"}},
+	)
+	err = cfg.Validate()
+	assert.ErrorContains(t, err, `sidecar channel "canary" is defined more than once`)
+	assert.ErrorContains(t, err, `sidecar channel "stable" is reserved`)
+	assert.ErrorContains(t, err, `sidecar channel "beta": sidecar "frontend" must be one of elector, texas, vault, wonderwall`)
+}
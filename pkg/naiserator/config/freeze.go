@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// FreezeWindow is a period during which rollouts are held.
+// A window without namespaces applies to the whole cluster.
+type FreezeWindow struct {
+	Name       string    `json:"name"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	Namespaces []string  `json:"namespaces"`
+}
+
+func (w FreezeWindow) Active(namespace string, now time.Time) bool {
+	if now.Before(w.Start) || !now.Before(w.End) {
+		return false
+	}
+	return len(w.Namespaces) == 0 || slices.Contains(w.Namespaces, namespace)
+}
+
+func (w FreezeWindow) Validate() error {
+	result := &multierror.Error{}
+
+	if len(w.Name) == 0 {
+		multierror.Append(result, fmt.Errorf("freeze window must have a name"))
+	}
+	if w.Start.IsZero() || w.End.IsZero() {
+		multierror.Append(result, fmt.Errorf("freeze window %q must have both start and end", w.Name))
+	} else if !w.End.After(w.Start) {
+		multierror.Append(result, fmt.Errorf("freeze window %q must end after it starts", w.Name))
+	}
+
+	return result.ErrorOrNil()
+}
+
+// ActiveFreezeWindow returns the active freeze window for the namespace that ends last, if any.
+func (c Config) ActiveFreezeWindow(namespace string, now time.Time) (FreezeWindow, bool) {
+	var active FreezeWindow
+	found := false
+
+	for _, window := range c.FreezeWindows {
+		if window.Active(namespace, now) && (!found || window.End.After(active.End)) {
+			active = window
+			found = true
+		}
+	}
+
+	return active, found
+}
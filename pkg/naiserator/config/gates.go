@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"hash/fnv"
+	"slices"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+type GateMode string
+
+const (
+	GateOff        GateMode = "off"
+	GateOn         GateMode = "on"
+	GateAllowlist  GateMode = "allowlist"
+	GatePercentage GateMode = "percentage"
+)
+
+// FeatureGate lets new generator behaviour be rolled out gradually.
+// Gates that are not configured are off.
+type FeatureGate struct {
+	Name       string   `json:"name"`
+	Mode       GateMode `json:"mode"`
+	Namespaces []string `json:"namespaces"`
+	Percentage int      `json:"percentage"`
+}
+
+// Enabled returns true if the gate is open for the given workload.
+// Only the operator decides which namespaces are allowed; namespaces cannot enable gates for themselves.
+// Percentage gates hash the workload identity, so a workload stays in or out of the rollout
+// across reconciles and restarts as long as the percentage doesn't change.
+func (g FeatureGate) Enabled(namespace, name string) bool {
+	switch g.Mode {
+	case GateOn:
+		return true
+	case GateAllowlist:
+		return slices.Contains(g.Namespaces, namespace)
+	case GatePercentage:
+		return gateBucket(g.Name, namespace, name) < g.Percentage
+	default:
+		return false
+	}
+}
+
+// gateBucket assigns the workload to one of 100 buckets. The gate name is part of the hash,
+// so that the same workloads aren't always first in line for every gate.
+func gateBucket(gate, namespace, name string) int {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(gate + "/" + namespace + "/" + name))
+	return int(hash.Sum32() % 100)
+}
+
+func (g FeatureGate) Validate() error {
+	result := &multierror.Error{}
+
+	if len(g.Name) == 0 {
+		multierror.Append(result, fmt.Errorf("feature gate must have a name"))
+	}
+
+	switch g.Mode {
+	case GateOff, GateOn:
+	case GateAllowlist:
+		if len(g.Namespaces) == 0 {
+			multierror.Append(result, fmt.Errorf("feature gate %q: allowlist mode requires namespaces", g.Name))
+		}
+	case GatePercentage:
+		if g.Percentage < 0 || g.Percentage > 100 {
+			multierror.Append(result, fmt.Errorf("feature gate %q: percentage must be between 0 and 100", g.Name))
+		}
+	default:
+		multierror.Append(result, fmt.Errorf("feature gate %q: invalid mode %q; must be one of %s, %s, %s or %s", g.Name, g.Mode, GateOff, GateOn, GateAllowlist, GatePercentage))
+	}
+
+	return result.ErrorOrNil()
+}
+
+// FeatureGate returns the named gate, or a closed gate if it is not configured.
+func (c Config) FeatureGate(name string) FeatureGate {
+	for _, gate := range c.FeatureGates {
+		if gate.Name == name {
+			return gate
+		}
+	}
+	return FeatureGate{Name: name, Mode: GateOff}
+}
+
+func validateFeatureGates(gates []FeatureGate) error {
+	result := &multierror.Error{}
+	seen := make(map[string]bool)
+
+	for _, gate := range gates {
+		multierror.Append(result, gate.Validate())
+		if seen[gate.Name] {
+			multierror.Append(result, fmt.Errorf("feature gate %q is defined more than once", gate.Name))
+		}
+		seen[gate.Name] = true
+	}
+
+	return result.ErrorOrNil()
+}
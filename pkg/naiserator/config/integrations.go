@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// Optional platform integrations. When soft-fail is enabled for one of these, a failure in the integration
+// degrades the workload instead of failing the rollout. All other integrations are critical.
+const (
+	IntegrationAiven      = "aiven"
+	IntegrationFQDNPolicy = "fqdn-policy"
+	IntegrationPostgres   = "postgres"
+	IntegrationPrometheus = "prometheus"
+)
+
+var OptionalIntegrations = []string{IntegrationAiven, IntegrationFQDNPolicy, IntegrationPostgres, IntegrationPrometheus}
+
+// SoftFail returns true if failures in the integration should degrade the workload instead of failing the rollout.
+// Soft-fail is enabled for the whole cluster in the configuration, or for a single workload with a comma-separated
+// list of integrations, where "*" means all optional integrations.
+func (c Config) SoftFail(integration, workloadSetting string) bool {
+	if !slices.Contains(OptionalIntegrations, integration) {
+		return false
+	}
+	if slices.Contains(c.SoftFailIntegrations, integration) {
+		return true
+	}
+	for item := range strings.SplitSeq(workloadSetting, ",") {
+		item = strings.TrimSpace(item)
+		if item == "*" || item == integration {
+			return true
+		}
+	}
+	return false
+}
+
+func validateSoftFailIntegrations(integrations []string) error {
+	result := &multierror.Error{}
+
+	for _, integration := range integrations {
+		if !slices.Contains(OptionalIntegrations, integration) {
+			multierror.Append(result, fmt.Errorf("soft-fail integration %q must be one of %s", integration, strings.Join(OptionalIntegrations, ", ")))
+		}
+	}
+
+	return result.ErrorOrNil()
+}
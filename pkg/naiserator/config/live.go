@@ -0,0 +1,119 @@
+package config
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Live holds configuration that can be changed while Naiserator is running.
+// Only the fields in reloadableFields are changed on reload; everything else requires a restart.
+type Live struct {
+	lock   sync.RWMutex
+	config Config
+}
+
+type reloadableField struct {
+	key   string
+	apply func(dst *Config, src Config)
+}
+
+// These settings only affect resources generated by subsequent reconciles, and are safe to change at any time.
+var reloadableFields = []reloadableField{
+	{"domain-ingressclass-mapping", func(dst *Config, src Config) { dst.DomainIngressClassMapping = src.DomainIngressClassMapping }},
+	{"feature-gates", func(dst *Config, src Config) { dst.FeatureGates = src.FeatureGates }},
+	{"freeze-windows", func(dst *Config, src Config) { dst.FreezeWindows = src.FreezeWindows }},
+	{GoogleCloudSQLProxyContainerImage, func(dst *Config, src Config) {
+		dst.GoogleCloudSQLProxyContainerImage = src.GoogleCloudSQLProxyContainerImage
+	}},
+	{LeaderElectionImage, func(dst *Config, src Config) { dst.LeaderElection.Image = src.LeaderElection.Image }},
+	{MaintenanceBackend, func(dst *Config, src Config) { dst.Maintenance.Backend = src.Maintenance.Backend }},
+	{ObservabilityLoggingDefaultDestination, func(dst *Config, src Config) {
+		dst.Observability.Logging.DefaultDestination = src.Observability.Logging.DefaultDestination
+	}},
+	{ObservabilityLoggingDestinations, func(dst *Config, src Config) {
+		dst.Observability.Logging.Destinations = src.Observability.Logging.Destinations
+	}},
+	{ObservabilityOtelDestinations, func(dst *Config, src Config) {
+		dst.Observability.Otel.Destinations = src.Observability.Otel.Destinations
+	}},
+	{"preview", func(dst *Config, src Config) { dst.Preview = src.Preview }},
+	{"progressive-delivery", func(dst *Config, src Config) { dst.ProgressiveDelivery = src.ProgressiveDelivery }},
+	{ProxyAddress, func(dst *Config, src Config) { dst.Proxy.Address = src.Proxy.Address }},
+	{ProxyExclude, func(dst *Config, src Config) { dst.Proxy.Exclude = src.Proxy.Exclude }},
+	{"sidecar-channels", func(dst *Config, src Config) { dst.SidecarChannels = src.SidecarChannels }},
+	{SoftFailIntegrations, func(dst *Config, src Config) { dst.SoftFailIntegrations = src.SoftFailIntegrations }},
+	{TexasImage, func(dst *Config, src Config) { dst.Texas.Image = src.Texas.Image }},
+	{VaultInitContainerImage, func(dst *Config, src Config) { dst.Vault.InitContainerImage = src.Vault.InitContainerImage }},
+	{WonderwallImage, func(dst *Config, src Config) { dst.Wonderwall.Image = src.Wonderwall.Image }},
+}
+
+func NewLive(cfg Config) *Live {
+	return &Live{
+		config: cfg,
+	}
+}
+
+// Get returns a copy of the current configuration.
+func (l *Live) Get() Config {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	return l.config
+}
+
+// Reload applies the reloadable settings from next on top of the current configuration,
+// and returns the keys of the settings that changed.
+// If the resulting configuration is invalid, the current configuration is kept.
+func (l *Live) Reload(next Config) ([]string, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	updated := l.config
+	changed := make([]string, 0)
+	for _, field := range reloadableFields {
+		before := updated
+		field.apply(&updated, next)
+		if !reflect.DeepEqual(before, updated) {
+			changed = append(changed, field.key)
+		}
+	}
+
+	if len(changed) == 0 {
+		return changed, nil
+	}
+
+	err := updated.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	l.config = updated
+
+	return changed, nil
+}
+
+// Watch reloads the configuration whenever the configuration file changes.
+func (l *Live) Watch() {
+	viper.OnConfigChange(func(event fsnotify.Event) {
+		var next Config
+		err := viper.Unmarshal(&next, decoderHook)
+		if err != nil {
+			log.Errorf("Reloading configuration from %s: %s", event.Name, err)
+			return
+		}
+
+		changed, err := l.Reload(next)
+		if err != nil {
+			log.Errorf("Reloaded configuration from %s is invalid; keeping current configuration: %s", event.Name, err)
+			return
+		}
+
+		if len(changed) > 0 {
+			log.Infof("Reloaded configuration from %s; changed settings: %v", event.Name, changed)
+		}
+	})
+	viper.WatchConfig()
+}
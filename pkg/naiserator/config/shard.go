@@ -0,0 +1,84 @@
+package config
+
+import (
+	"fmt"
+	"hash/fnv"
+	"slices"
+
+	"github.com/hashicorp/go-multierror"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Shard restricts a Naiserator instance to a slice of the workloads in the cluster,
+// so that several instances can run in parallel without overlapping.
+// All configured restrictions must match for a workload to belong to the shard.
+type Shard struct {
+	Namespaces    []string `json:"namespaces"`
+	LabelSelector string   `json:"label-selector"`
+	Index         int      `json:"index"`
+	Count         int      `json:"count"`
+}
+
+func (s Shard) Enabled() bool {
+	return len(s.Namespaces) > 0 || len(s.LabelSelector) > 0 || s.Count > 1
+}
+
+// OwnsNamespace returns true if workloads in the namespace belong to this shard.
+// Whole namespaces are assigned to the same shard, so that workloads that look each other up by name
+// are always handled by the same instance.
+func (s Shard) OwnsNamespace(namespace string) bool {
+	if len(s.Namespaces) > 0 && !slices.Contains(s.Namespaces, namespace) {
+		return false
+	}
+	if s.Count > 1 && jumpHash(namespace, s.Count) != s.Index {
+		return false
+	}
+	return true
+}
+
+// Owns returns true if the workload belongs to this shard.
+func (s Shard) Owns(namespace string, workloadLabels map[string]string) bool {
+	if !s.OwnsNamespace(namespace) {
+		return false
+	}
+	selector, err := s.Selector()
+	return err == nil && selector.Matches(labels.Set(workloadLabels))
+}
+
+// Selector returns the label selector for workloads in this shard.
+func (s Shard) Selector() (labels.Selector, error) {
+	return labels.Parse(s.LabelSelector)
+}
+
+func (s Shard) Validate() error {
+	result := &multierror.Error{}
+
+	if s.Count < 0 || (s.Count > 0 && (s.Index < 0 || s.Index >= s.Count)) {
+		multierror.Append(result, fmt.Errorf("shard index must be between 0 and shard count - 1"))
+	}
+
+	_, err := s.Selector()
+	if err != nil {
+		multierror.Append(result, fmt.Errorf("shard label selector: %w", err))
+	}
+
+	return result.ErrorOrNil()
+}
+
+// jumpHash assigns the key to one of the buckets using jump consistent hashing,
+// so that only a minimal number of namespaces move when the shard count changes.
+// See https://arxiv.org/abs/1406.2294
+func jumpHash(key string, buckets int) int {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(key))
+	k := hash.Sum64()
+
+	b, j := int64(-1), int64(0)
+	for j < int64(buckets) {
+		b = j
+		k = k*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((k>>33)+1)))
+	}
+
+	return int(b)
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// Sidecars whose images can be overridden by a sidecar channel.
+const (
+	SidecarElector    = "elector"
+	SidecarTexas      = "texas"
+	SidecarVault      = "vault"
+	SidecarWonderwall = "wonderwall"
+
+	// DefaultSidecarChannel is the name of the images configured for the cluster.
+	DefaultSidecarChannel = "stable"
+)
+
+var Sidecars = []string{SidecarElector, SidecarTexas, SidecarVault, SidecarWonderwall}
+
+// SidecarChannel is a named set of sidecar images, so that new sidecar releases can soak on the workloads
+// that opt into the channel before they are rolled out everywhere.
+// Sidecars without an image in the channel use the image configured for the cluster.
+type SidecarChannel struct {
+	Name   string            `json:"name"`
+	Images map[string]string `json:"images"`
+}
+
+// WithSidecarChannel returns a copy of the configuration using the images of the named sidecar channel.
+func (c Config) WithSidecarChannel(name string) (Config, error) {
+	if len(name) == 0 || name == DefaultSidecarChannel {
+		return c, nil
+	}
+
+	i := slices.IndexFunc(c.SidecarChannels, func(channel SidecarChannel) bool {
+		return channel.Name == name
+	})
+	if i < 0 {
+		return c, fmt.Errorf("sidecar channel %q does not exist in cluster", name)
+	}
+
+	for sidecar, image := range c.SidecarChannels[i].Images {
+		switch sidecar {
+		case SidecarElector:
+			c.LeaderElection.Image = image
+		case SidecarTexas:
+			c.Texas.Image = image
+		case SidecarVault:
+			c.Vault.InitContainerImage = image
+		case SidecarWonderwall:
+			c.Wonderwall.Image = image
+		}
+	}
+
+	return c, nil
+}
+
+// SidecarImages returns the configured image of each sidecar that has one.
+func (c Config) SidecarImages() map[string]string {
+	images := map[string]string{
+		SidecarElector:    c.LeaderElection.Image,
+		SidecarTexas:      c.Texas.Image,
+		SidecarVault:      c.Vault.InitContainerImage,
+		SidecarWonderwall: c.Wonderwall.Image,
+	}
+	maps.DeleteFunc(images, func(_, image string) bool {
+		return len(image) == 0
+	})
+	return images
+}
+
+func validateSidecarChannels(channels []SidecarChannel) error {
+	result := &multierror.Error{}
+	names := make(map[string]bool)
+
+	for i, channel := range channels {
+		switch {
+		case len(channel.Name) == 0:
+			multierror.Append(result, fmt.Errorf("sidecar channel %d must have a name", i))
+		case channel.Name == DefaultSidecarChannel:
+			multierror.Append(result, fmt.Errorf("sidecar channel %q is reserved for the images configured for the cluster", DefaultSidecarChannel))
+		case names[channel.Name]:
+			multierror.Append(result, fmt.Errorf("sidecar channel %q is defined more than once", channel.Name))
+		}
+		names[channel.Name] = true
+
+		for sidecar, image := range channel.Images {
+			if !slices.Contains(Sidecars, sidecar) {
+				multierror.Append(result, fmt.Errorf("sidecar channel %q: sidecar %q must be one of %s", channel.Name, sidecar, strings.Join(Sidecars, ", ")))
+			}
+			if len(image) == 0 {
+				multierror.Append(result, fmt.Errorf("sidecar channel %q: image for %s not specified", channel.Name, sidecar))
+			}
+		}
+	}
+
+	return result.ErrorOrNil()
+}
@@ -2,8 +2,11 @@ package config
 
 import (
 	"fmt"
+	"net/url"
+	"slices"
 
 	"github.com/hashicorp/go-multierror"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 func (v Vault) Validate() error {
@@ -27,3 +30,196 @@ func (v Vault) Validate() error {
 
 	return result.ErrorOrNil()
 }
+
+// Warnings returns settings that are missing for features that are always offered to workloads.
+// Workloads only fail to synchronize when they use such a feature, so these don't prevent Naiserator from starting.
+func (c Config) Warnings() []string {
+	warnings := make([]string, 0)
+
+	if len(c.LeaderElection.Image) == 0 {
+		warnings = append(warnings, "leader election image not specified; applications with leader election will fail to synchronize")
+	}
+
+	return warnings
+}
+
+// Validate checks that every enabled feature has the configuration it needs.
+// Misconfigurations are otherwise only discovered when a workload tries to use the feature.
+func (c Config) Validate() error {
+	result := &multierror.Error{}
+
+	if len(c.DomainIngressClassMapping) == 0 {
+		multierror.Append(result, fmt.Errorf("no gateway mappings defined; will not be able to set the right gateway on the ingress"))
+	}
+	for i, mapping := range c.DomainIngressClassMapping {
+		if len(mapping.DomainSuffix) == 0 || len(mapping.IngressClass) == 0 {
+			multierror.Append(result, fmt.Errorf("gateway mapping %d must have both domain suffix and ingress class", i))
+		}
+	}
+
+	if !slices.Contains([]CapabilityCheck{CapabilityCheckOff, CapabilityCheckWarn, CapabilityCheckFail, CapabilityCheckDisable}, c.CapabilityCheck) {
+		multierror.Append(result, fmt.Errorf("capability check must be one of off, warn, fail or disable"))
+	}
+
+	if c.MaxConcurrentReconciles < 1 {
+		multierror.Append(result, fmt.Errorf("max concurrent reconciles must be at least 1"))
+	}
+
+	if c.Synchronizer.SynchronizationTimeout <= 0 || c.Synchronizer.RolloutTimeout <= 0 || c.Synchronizer.RolloutCheckInterval <= 0 {
+		multierror.Append(result, fmt.Errorf("synchronizer timeouts and intervals must be positive"))
+	}
+
+	if c.Features.Vault {
+		multierror.Append(result, c.Vault.Validate())
+	}
+
+	if c.Features.GCP {
+		if len(c.GoogleProjectID) == 0 {
+			multierror.Append(result, fmt.Errorf("gcp is enabled, but google project id not specified"))
+		}
+		if len(c.GoogleCloudSQLProxyContainerImage) == 0 {
+			multierror.Append(result, fmt.Errorf("gcp is enabled, but cloud sql proxy container image not specified"))
+		}
+	}
+
+	if c.Features.Texas && len(c.Texas.Image) == 0 {
+		multierror.Append(result, fmt.Errorf("texas is enabled, but texas image not specified"))
+	}
+
+	if c.Features.Wonderwall && len(c.Wonderwall.Image) == 0 {
+		multierror.Append(result, fmt.Errorf("wonderwall is enabled, but wonderwall image not specified"))
+	}
+
+	if c.Features.IDPorten && !c.Features.Wonderwall {
+		multierror.Append(result, fmt.Errorf("idporten requires wonderwall to be enabled"))
+	}
+
+	if c.Features.NetworkPolicy && len(c.NaisNamespace) == 0 {
+		multierror.Append(result, fmt.Errorf("network policies are enabled, but nais namespace not specified"))
+	}
+
+	if c.ControllerLeaderElection.Enabled {
+		multierror.Append(result, c.ControllerLeaderElection.Validate())
+	}
+
+	multierror.Append(result, c.LifecycleEvents.Validate())
+	multierror.Append(result, c.Observability.Validate())
+	multierror.Append(result, c.Maintenance.Validate())
+	multierror.Append(result, c.Preview.Validate())
+	multierror.Append(result, c.ProgressiveDelivery.Validate())
+	multierror.Append(result, c.Shard.Validate())
+	multierror.Append(result, validateFeatureGates(c.FeatureGates))
+	multierror.Append(result, validateSoftFailIntegrations(c.SoftFailIntegrations))
+	multierror.Append(result, validateSidecarChannels(c.SidecarChannels))
+
+	for _, window := range c.FreezeWindows {
+		multierror.Append(result, window.Validate())
+	}
+
+	return result.ErrorOrNil()
+}
+
+func (l ControllerLeaderElection) Validate() error {
+	result := &multierror.Error{}
+
+	if len(l.LeaseName) == 0 {
+		multierror.Append(result, fmt.Errorf("leader election is enabled, but lease name not specified"))
+	}
+	if l.RetryPeriod <= 0 || l.RenewDeadline <= l.RetryPeriod || l.LeaseDuration <= l.RenewDeadline {
+		multierror.Append(result, fmt.Errorf("leader election requires lease duration > renew deadline > retry period > 0"))
+	}
+
+	return result.ErrorOrNil()
+}
+
+func (l LifecycleEvents) Validate() error {
+	result := &multierror.Error{}
+
+	if len(l.SinkURL) > 0 {
+		sink, err := url.Parse(l.SinkURL)
+		if err != nil || (sink.Scheme != "http" && sink.Scheme != "https") {
+			multierror.Append(result, fmt.Errorf("lifecycle events sink url must be an http or https url"))
+		}
+		if len(l.SpoolDirectory) > 0 {
+			multierror.Append(result, fmt.Errorf("lifecycle events can be sent to either a sink url or a spool directory, not both"))
+		}
+	}
+	if (len(l.SinkURL) > 0 || len(l.SpoolDirectory) > 0) && l.QueueSize < 1 {
+		multierror.Append(result, fmt.Errorf("lifecycle events queue size must be at least 1"))
+	}
+
+	return result.ErrorOrNil()
+}
+
+func (m Maintenance) Validate() error {
+	if len(m.Backend) > 0 && len(validation.IsDNS1123Subdomain(m.Backend)) > 0 {
+		return fmt.Errorf("maintenance backend must be a DNS name, such as maintenance.nais-system.svc.cluster.local")
+	}
+	return nil
+}
+
+func (p Preview) Validate() error {
+	if p.DefaultTTL < 0 || p.MaxTTL < 0 {
+		return fmt.Errorf("preview default ttl and max ttl must not be negative")
+	}
+	if p.DefaultTTL > p.MaxTTL {
+		return fmt.Errorf("preview default ttl must not be longer than max ttl")
+	}
+	return nil
+}
+
+func (p ProgressiveDelivery) Validate() error {
+	result := &multierror.Error{}
+
+	if len(p.PrometheusURL) > 0 {
+		prometheus, err := url.Parse(p.PrometheusURL)
+		if err != nil || (prometheus.Scheme != "http" && prometheus.Scheme != "https") {
+			multierror.Append(result, fmt.Errorf("progressive delivery prometheus url must be an http or https url"))
+		}
+	}
+	for i, weight := range p.Steps {
+		if weight < 1 || weight > 99 || (i > 0 && weight <= p.Steps[i-1]) {
+			multierror.Append(result, fmt.Errorf("progressive delivery steps must be increasing percentages between 1 and 99"))
+			break
+		}
+	}
+	if p.StepInterval < 0 {
+		multierror.Append(result, fmt.Errorf("progressive delivery step interval must not be negative"))
+	}
+
+	return result.ErrorOrNil()
+}
+
+func (o Observability) Validate() error {
+	result := &multierror.Error{}
+
+	if o.Otel.AutoInstrumentation.Enabled {
+		if !o.Otel.Enabled {
+			multierror.Append(result, fmt.Errorf("otel auto-instrumentation requires otel to be enabled"))
+		}
+		if len(o.Otel.AutoInstrumentation.AppConfig) == 0 {
+			multierror.Append(result, fmt.Errorf("otel auto-instrumentation is enabled, but app config not specified"))
+		}
+	}
+
+	if len(o.Logging.DefaultDestination) > 0 && !slices.Contains(o.Logging.Destinations, o.Logging.DefaultDestination) {
+		multierror.Append(result, fmt.Errorf("default logging destination %q is not among the logging destinations", o.Logging.DefaultDestination))
+	}
+
+	if !o.Otel.Enabled {
+		return result.ErrorOrNil()
+	}
+
+	collector := o.Otel.Collector
+	if len(collector.Service) == 0 || len(collector.Namespace) == 0 {
+		multierror.Append(result, fmt.Errorf("otel is enabled, but collector service and namespace not specified"))
+	}
+	if collector.Port <= 0 {
+		multierror.Append(result, fmt.Errorf("otel is enabled, but collector port not specified"))
+	}
+	if len(collector.Protocol) == 0 {
+		multierror.Append(result, fmt.Errorf("otel is enabled, but collector protocol not specified"))
+	}
+
+	return result.ErrorOrNil()
+}
@@ -0,0 +1,214 @@
+// Package policy evaluates cluster-wide guardrails, as declared in the naiserator configuration,
+// against Applications and Naisjobs.
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	k8sResource "k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/nais/naiserator/pkg/annotations"
+	"github.com/nais/naiserator/pkg/naiserator/config"
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+
+	runAsUserAnnotation = annotations.RunAsUser
+)
+
+// Workload is the subset of an Application or Naisjob that policy rules are evaluated against.
+type Workload struct {
+	Kind        string
+	Name        string
+	Namespace   string
+	Image       string
+	Annotations map[string]string
+
+	// MaxReplicas is nil for workloads that are not scaled by replica count, e.g. Naisjobs.
+	MaxReplicas *int
+
+	CPURequest    string
+	MemoryRequest string
+	MemoryLimit   string
+
+	LoggingEnabled      bool
+	LoggingDestinations []string
+}
+
+// Violation is a single broken constraint from a policy rule.
+type Violation struct {
+	Rule     string
+	Severity string
+	Message  string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("policy %q: %s", v.Rule, v.Message)
+}
+
+type Violations []Violation
+
+// Errors returns violations that must block the rollout.
+func (v Violations) Errors() Violations {
+	return v.filter(SeverityError)
+}
+
+// Warnings returns violations that should be reported, but allow the rollout to proceed.
+func (v Violations) Warnings() Violations {
+	return v.filter(SeverityWarning)
+}
+
+// Err returns an error describing all blocking violations, or nil if there are none.
+func (v Violations) Err() error {
+	errs := v.Errors()
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(errs.Messages(), "; "))
+}
+
+// Messages returns a human-readable message for each violation.
+func (v Violations) Messages() []string {
+	messages := make([]string, 0, len(v))
+	for _, violation := range v {
+		messages = append(messages, violation.String())
+	}
+	return messages
+}
+
+func (v Violations) filter(severity string) Violations {
+	filtered := make(Violations, 0)
+	for _, violation := range v {
+		if violation.Severity == severity {
+			filtered = append(filtered, violation)
+		}
+	}
+	return filtered
+}
+
+// Validate checks that the policy rules are well-formed.
+func Validate(rules []config.PolicyRule) error {
+	seen := make(map[string]bool)
+	for i, rule := range rules {
+		if len(rule.Name) == 0 {
+			return fmt.Errorf("policy rule %d: name is required", i)
+		}
+		if seen[rule.Name] {
+			return fmt.Errorf("policy rule %q: duplicate name", rule.Name)
+		}
+		seen[rule.Name] = true
+
+		switch rule.Severity {
+		case "", SeverityError, SeverityWarning:
+		default:
+			return fmt.Errorf("policy rule %q: severity must be %q or %q", rule.Name, SeverityError, SeverityWarning)
+		}
+
+		if len(rule.MaxMemory) > 0 {
+			if _, err := k8sResource.ParseQuantity(rule.MaxMemory); err != nil {
+				return fmt.Errorf("policy rule %q: max memory: %w", rule.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Evaluate returns all violations of the policy rules that apply to the workload's namespace.
+// Rules without a severity are treated as errors.
+func Evaluate(rules []config.PolicyRule, workload Workload) Violations {
+	violations := make(Violations, 0)
+
+	for _, rule := range rules {
+		if len(rule.Namespaces) > 0 && !slices.Contains(rule.Namespaces, workload.Namespace) {
+			continue
+		}
+
+		severity := rule.Severity
+		if len(severity) == 0 {
+			severity = SeverityError
+		}
+
+		for _, message := range evaluateRule(rule, workload) {
+			violations = append(violations, Violation{
+				Rule:     rule.Name,
+				Severity: severity,
+				Message:  message,
+			})
+		}
+	}
+
+	return violations
+}
+
+func evaluateRule(rule config.PolicyRule, workload Workload) []string {
+	messages := make([]string, 0)
+
+	if len(rule.AllowedImageRegistries) > 0 && !allowedRegistry(rule.AllowedImageRegistries, workload.Image) {
+		messages = append(messages, fmt.Sprintf("image %q is not from an allowed registry (%s)", workload.Image, strings.Join(rule.AllowedImageRegistries, ", ")))
+	}
+
+	if rule.RequireResourceRequests {
+		if len(workload.CPURequest) == 0 {
+			messages = append(messages, "cpu request must be set")
+		}
+		if len(workload.MemoryRequest) == 0 {
+			messages = append(messages, "memory request must be set")
+		}
+	}
+
+	if rule.MaxReplicas > 0 && workload.MaxReplicas != nil && *workload.MaxReplicas > rule.MaxReplicas {
+		messages = append(messages, fmt.Sprintf("max replicas %d exceeds the allowed maximum of %d", *workload.MaxReplicas, rule.MaxReplicas))
+	}
+
+	if len(rule.MaxMemory) > 0 {
+		messages = append(messages, exceedsMemory(rule.MaxMemory, "memory request", workload.MemoryRequest)...)
+		messages = append(messages, exceedsMemory(rule.MaxMemory, "memory limit", workload.MemoryLimit)...)
+	}
+
+	if uid, found := workload.Annotations[runAsUserAnnotation]; found && slices.Contains(rule.ForbiddenRunAsUser, uid) {
+		messages = append(messages, fmt.Sprintf("annotation %s=%s is not allowed", runAsUserAnnotation, uid))
+	}
+
+	for _, destination := range rule.RequiredLoggingDestinations {
+		if !workload.LoggingEnabled || !slices.Contains(workload.LoggingDestinations, destination) {
+			messages = append(messages, fmt.Sprintf("logging destination %q is required", destination))
+		}
+	}
+
+	return messages
+}
+
+func allowedRegistry(registries []string, image string) bool {
+	for _, registry := range registries {
+		if strings.HasPrefix(image, strings.TrimSuffix(registry, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func exceedsMemory(maxMemory, field, value string) []string {
+	if len(value) == 0 {
+		return nil
+	}
+
+	limit, err := k8sResource.ParseQuantity(maxMemory)
+	if err != nil {
+		return []string{fmt.Sprintf("invalid max memory %q in policy: %s", maxMemory, err)}
+	}
+
+	quantity, err := k8sResource.ParseQuantity(value)
+	if err != nil {
+		return []string{fmt.Sprintf("invalid %s %q: %s", field, value, err)}
+	}
+
+	if quantity.Cmp(limit) > 0 {
+		return []string{fmt.Sprintf("%s %s exceeds the allowed maximum of %s", field, value, maxMemory)}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package policy_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nais/naiserator/pkg/naiserator/config"
+	"github.com/nais/naiserator/pkg/policy"
+)
+
+func TestEvaluate(t *testing.T) {
+	workload := policy.Workload{
+		Name:          "myapplication",
+		Namespace:     "mynamespace",
+		Image:         "docker.io/navikt/myapplication:1.2.3",
+		Annotations:   map[string]string{"nais.io/run-as-user": "0"},
+		MaxReplicas:   new(10),
+		MemoryRequest: "256Mi",
+		MemoryLimit:   "2Gi",
+	}
+
+	testCases := []struct {
+		name     string
+		rules    []config.PolicyRule
+		expected []string
+		errors   int
+	}{
+		{
+			name:     "no rules",
+			expected: []string{},
+		},
+		{
+			name: "rule for other namespace is ignored",
+			rules: []config.PolicyRule{
+				{Name: "replicas", Namespaces: []string{"other"}, MaxReplicas: 2},
+			},
+			expected: []string{},
+		},
+		{
+			name: "image registry",
+			rules: []config.PolicyRule{
+				{Name: "registry", AllowedImageRegistries: []string{"europe-north1-docker.pkg.dev/"}},
+			},
+			expected: []string{`policy "registry": image "docker.io/navikt/myapplication:1.2.3" is not from an allowed registry (europe-north1-docker.pkg.dev/)`},
+			errors:   1,
+		},
+		{
+			name: "allowed image registry",
+			rules: []config.PolicyRule{
+				{Name: "registry", AllowedImageRegistries: []string{"ghcr.io", "docker.io"}},
+			},
+			expected: []string{},
+		},
+		{
+			name: "resource requests as warning",
+			rules: []config.PolicyRule{
+				{Name: "requests", Severity: policy.SeverityWarning, RequireResourceRequests: true},
+			},
+			expected: []string{`policy "requests": cpu request must be set`},
+		},
+		{
+			name: "replicas and memory",
+			rules: []config.PolicyRule{
+				{Name: "limits", Namespaces: []string{"mynamespace"}, MaxReplicas: 4, MaxMemory: "1Gi"},
+			},
+			expected: []string{
+				`policy "limits": max replicas 10 exceeds the allowed maximum of 4`,
+				`policy "limits": memory limit 2Gi exceeds the allowed maximum of 1Gi`,
+			},
+			errors: 2,
+		},
+		{
+			name: "forbidden run-as-user and logging destinations",
+			rules: []config.PolicyRule{
+				{Name: "security", ForbiddenRunAsUser: []string{"0"}, RequiredLoggingDestinations: []string{"secure_logs"}},
+			},
+			expected: []string{
+				`policy "security": annotation nais.io/run-as-user=0 is not allowed`,
+				`policy "security": logging destination "secure_logs" is required`,
+			},
+			errors: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			violations := policy.Evaluate(tc.rules, workload)
+			assert.Equal(t, tc.expected, violations.Messages())
+			assert.Len(t, violations.Errors(), tc.errors)
+			if tc.errors == 0 {
+				assert.NoError(t, violations.Err())
+			} else {
+				assert.Error(t, violations.Err())
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, policy.Validate([]config.PolicyRule{{Name: "a"}, {Name: "b", Severity: policy.SeverityWarning, MaxMemory: "1Gi"}}))
+	assert.EqualError(t, policy.Validate([]config.PolicyRule{{}}), "policy rule 0: name is required")
+	assert.EqualError(t, policy.Validate([]config.PolicyRule{{Name: "a"}, {Name: "a"}}), `policy rule "a": duplicate name`)
+	assert.EqualError(t, policy.Validate([]config.PolicyRule{{Name: "a", Severity: "fatal"}}), `policy rule "a": severity must be "error" or "warning"`)
+	assert.Error(t, policy.Validate([]config.PolicyRule{{Name: "a", MaxMemory: "lots"}}))
+}
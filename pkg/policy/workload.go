@@ -0,0 +1,71 @@
+package policy
+
+import (
+	nais_io_v1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+
+	"github.com/nais/naiserator/pkg/resourcecreator/resource"
+)
+
+type imageSource interface {
+	GetImage() string
+	GetEffectiveImage() string
+}
+
+type replicaSource interface {
+	GetReplicas() *nais_io_v1.Replicas
+}
+
+type resourcesSource interface {
+	GetResources() *nais_io_v1.ResourceRequirements
+}
+
+type observabilitySource interface {
+	GetObservability() *nais_io_v1.Observability
+}
+
+// NewWorkload extracts the fields that policy rules are evaluated against from an Application or Naisjob.
+// Defaults should be applied to the source before calling this function.
+func NewWorkload(source resource.Source) Workload {
+	workload := Workload{
+		Kind:        source.GetObjectKind().GroupVersionKind().Kind,
+		Name:        source.GetName(),
+		Namespace:   source.GetNamespace(),
+		Annotations: source.GetAnnotations(),
+	}
+
+	if src, ok := source.(imageSource); ok {
+		workload.Image = src.GetEffectiveImage()
+		if len(workload.Image) == 0 {
+			workload.Image = src.GetImage()
+		}
+	}
+
+	if src, ok := source.(replicaSource); ok {
+		if replicas := src.GetReplicas(); replicas != nil {
+			workload.MaxReplicas = replicas.Max
+		}
+	}
+
+	if src, ok := source.(resourcesSource); ok {
+		if resources := src.GetResources(); resources != nil {
+			if resources.Requests != nil {
+				workload.CPURequest = resources.Requests.Cpu
+				workload.MemoryRequest = resources.Requests.Memory
+			}
+			if resources.Limits != nil {
+				workload.MemoryLimit = resources.Limits.Memory
+			}
+		}
+	}
+
+	if src, ok := source.(observabilitySource); ok {
+		if obs := src.GetObservability(); obs != nil && obs.Logging != nil {
+			workload.LoggingEnabled = obs.Logging.Enabled
+			for _, destination := range obs.Logging.Destinations {
+				workload.LoggingDestinations = append(workload.LoggingDestinations, destination.ID)
+			}
+		}
+	}
+
+	return workload
+}
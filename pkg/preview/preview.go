@@ -0,0 +1,71 @@
+// Package preview handles Applications deployed as ephemeral previews of another Application,
+// such as the deployment of a pull request.
+//
+// A preview of the Application myapp for pull request 123 is an Application named myapp-pr-123 with the annotation
+// naiserator.nais.io/preview set to 123. It is served on myapp-pr-123 in the domains of myapp's ingresses,
+// and is deleted when its time to live has passed since its last deployment.
+package preview
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nais/naiserator/pkg/annotations"
+	"github.com/nais/naiserator/pkg/naiserator/config"
+)
+
+// Enabled returns true if the workload is a preview.
+func Enabled(objectAnnotations map[string]string) bool {
+	return len(objectAnnotations[annotations.Preview]) > 0
+}
+
+// Name returns the name of the preview of an application for a pull request.
+func Name(parent, pullRequest string) string {
+	return fmt.Sprintf("%s-pr-%s", parent, pullRequest)
+}
+
+// ParentName returns the name of the application that a preview is a preview of.
+func ParentName(name, pullRequest string) (string, error) {
+	suffix := Name("", pullRequest)
+	parent, ok := strings.CutSuffix(name, suffix)
+	if !ok || len(parent) == 0 {
+		return "", fmt.Errorf("the preview for pull request %s must be named <application>%s, not %s", pullRequest, suffix, name)
+	}
+	return parent, nil
+}
+
+// Host returns the hostname of a preview, which replaces the first label of the parent's hostname.
+func Host(parentHost, name string) string {
+	_, domain, ok := strings.Cut(parentHost, ".")
+	if !ok {
+		return name
+	}
+	return name + "." + domain
+}
+
+// TTL returns how long a preview lives after its last deployment.
+func TTL(objectAnnotations map[string]string, cfg config.Preview) time.Duration {
+	ttl := cfg.DefaultTTL
+	if value, ok := objectAnnotations[annotations.PreviewTTL]; ok {
+		// Invalid values are reported by annotations.Validate; fall back to the default.
+		if duration, err := time.ParseDuration(value); err == nil && duration > 0 {
+			ttl = duration
+		}
+	}
+	if cfg.MaxTTL > 0 {
+		ttl = min(ttl, cfg.MaxTTL)
+	}
+	return ttl
+}
+
+// Expiry returns when a preview is deleted. The time to live counts from the last deployment,
+// as recorded by the admission webhook, or from the creation of the preview.
+func Expiry(objectAnnotations map[string]string, created time.Time, cfg config.Preview) time.Time {
+	deployed := created
+	timestamp, err := time.Parse(time.RFC3339, objectAnnotations[annotations.DeployTimestamp])
+	if err == nil && timestamp.After(deployed) {
+		deployed = timestamp
+	}
+	return deployed.Add(TTL(objectAnnotations, cfg))
+}
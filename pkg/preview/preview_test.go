@@ -0,0 +1,54 @@
+package preview_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nais/naiserator/pkg/annotations"
+	"github.com/nais/naiserator/pkg/naiserator/config"
+	"github.com/nais/naiserator/pkg/preview"
+)
+
+var cfg = config.Preview{
+	DefaultTTL: 72 * time.Hour,
+	MaxTTL:     14 * 24 * time.Hour,
+}
+
+func TestParentName(t *testing.T) {
+	parent, err := preview.ParentName("myapp-pr-123", "123")
+	assert.NoError(t, err)
+	assert.Equal(t, "myapp", parent)
+
+	_, err = preview.ParentName("myapp-pr-124", "123")
+	assert.ErrorContains(t, err, "the preview for pull request 123 must be named <application>-pr-123, not myapp-pr-124")
+
+	_, err = preview.ParentName("-pr-123", "123")
+	assert.Error(t, err)
+}
+
+func TestHost(t *testing.T) {
+	assert.Equal(t, "myapp-pr-123.intern.nav.no", preview.Host("myapp.intern.nav.no", "myapp-pr-123"))
+	assert.Equal(t, "myapp-pr-123", preview.Host("localhost", "myapp-pr-123"))
+}
+
+func TestTTL(t *testing.T) {
+	assert.Equal(t, 72*time.Hour, preview.TTL(map[string]string{}, cfg))
+	assert.Equal(t, 2*time.Hour, preview.TTL(map[string]string{annotations.PreviewTTL: "2h"}, cfg))
+	assert.Equal(t, 72*time.Hour, preview.TTL(map[string]string{annotations.PreviewTTL: "-2h"}, cfg))
+	assert.Equal(t, cfg.MaxTTL, preview.TTL(map[string]string{annotations.PreviewTTL: "1000h"}, cfg))
+}
+
+func TestExpiry(t *testing.T) {
+	created := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	expiry := preview.Expiry(map[string]string{}, created, cfg)
+	assert.Equal(t, created.Add(72*time.Hour), expiry)
+
+	expiry = preview.Expiry(map[string]string{
+		annotations.DeployTimestamp: "2026-10-20T12:00:00Z",
+		annotations.PreviewTTL:      "1h",
+	}, created, cfg)
+	assert.Equal(t, time.Date(2026, 10, 20, 13, 0, 0, 0, time.UTC), expiry)
+}
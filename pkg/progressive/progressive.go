@@ -0,0 +1,297 @@
+// Package progressive decides how far a canary or blue-green rollout of an Application has come,
+// and whether the new version is promoted or rolled back.
+//
+// During a progressive rollout, the new version runs in a canary Deployment next to the stable Deployment,
+// and an increasing share of the ingress traffic is sent to it. Each step lasts until the canary is ready,
+// the step interval has passed, and the analysis query supplied by the team, if any, returns any samples.
+// After the last step, all traffic goes to the canary while the stable Deployment is updated to the new version.
+package progressive
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+
+	"github.com/nais/naiserator/pkg/annotations"
+	"github.com/nais/naiserator/pkg/naiserator/config"
+)
+
+// Phase of a progressive rollout.
+type Phase string
+
+const (
+	// PhaseProgressing sends a share of the ingress traffic to the canary.
+	PhaseProgressing Phase = "Progressing"
+	// PhasePromoting sends all ingress traffic to the canary while the stable Deployment is updated.
+	PhasePromoting Phase = "Promoting"
+	// PhasePromoted runs the new version in the stable Deployment only.
+	PhasePromoted Phase = "Promoted"
+	// PhaseRolledBack runs the previous version in the stable Deployment only.
+	PhaseRolledBack Phase = "RolledBack"
+)
+
+// Done returns true if the rollout has ended, and the canary is no longer needed.
+func (p Phase) Done() bool {
+	return p == PhasePromoted || p == PhaseRolledBack
+}
+
+// Bookkeeping annotations on the canary Deployment, so that the rollout continues where it left off.
+const (
+	PhaseAnnotation       = "naiserator.nais.io/progressive-phase"
+	StepAnnotation        = "naiserator.nais.io/progressive-step"
+	StepStartedAnnotation = "naiserator.nais.io/progressive-step-started"
+)
+
+// TrackLabel tells pods of the stable and canary Deployments apart, so that each can have its own Service.
+const (
+	TrackLabel  = "naiserator.nais.io/track"
+	TrackStable = "stable"
+	TrackCanary = "canary"
+)
+
+// CheckInterval is how often a rollout that waits for pods to become ready is checked.
+const CheckInterval = 30 * time.Second
+
+// Analysis runs a query supplied by the team, and returns true if the canary is healthy.
+type Analysis func(ctx context.Context, query string) (bool, error)
+
+// Settings of a progressive rollout.
+type Settings struct {
+	Strategy     string
+	Steps        []int
+	StepInterval time.Duration
+	Query        string
+}
+
+// Enabled returns true if the workload annotations select a progressive delivery strategy.
+func Enabled(workloadAnnotations map[string]string) bool {
+	switch workloadAnnotations[annotations.DeliveryStrategy] {
+	case annotations.DeliveryStrategyCanary, annotations.DeliveryStrategyBlueGreen:
+		return true
+	default:
+		return false
+	}
+}
+
+// NewSettings reads the rollout settings from the workload annotations, falling back to the cluster configuration.
+// Blue-green rollouts have a single step where the new version gets no ingress traffic until it is verified.
+func NewSettings(workloadAnnotations map[string]string, cfg config.ProgressiveDelivery) (Settings, error) {
+	settings := Settings{
+		Strategy:     workloadAnnotations[annotations.DeliveryStrategy],
+		Steps:        cfg.Steps,
+		StepInterval: cfg.StepInterval,
+		Query:        workloadAnnotations[annotations.CanaryAnalysisQuery],
+	}
+
+	if value, found := workloadAnnotations[annotations.CanarySteps]; found {
+		steps, err := annotations.ParseWeights(value)
+		if err != nil {
+			return settings, fmt.Errorf("%s: %w", annotations.CanarySteps, err)
+		}
+		settings.Steps = steps
+	}
+
+	if value, found := workloadAnnotations[annotations.CanaryStepInterval]; found {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			return settings, fmt.Errorf("%s: invalid duration %q", annotations.CanaryStepInterval, value)
+		}
+		settings.StepInterval = interval
+	}
+
+	if settings.Strategy == annotations.DeliveryStrategyBlueGreen {
+		settings.Steps = []int{0}
+	}
+
+	if len(settings.Steps) == 0 {
+		return settings, fmt.Errorf("no canary steps configured in cluster; set %s", annotations.CanarySteps)
+	}
+
+	if len(settings.Query) > 0 && len(cfg.PrometheusURL) == 0 {
+		return settings, fmt.Errorf("%s is set, but no Prometheus is configured for progressive delivery in cluster", annotations.CanaryAnalysisQuery)
+	}
+
+	return settings, nil
+}
+
+// Observed is the progress of the rollout so far.
+type Observed struct {
+	// Phase is empty if the rollout of this deployment hasn't started.
+	Phase       Phase
+	Step        int
+	StepStarted time.Time
+	// Message is kept when the rollout has ended.
+	Message string
+	// CanaryReady is set when all replicas of the canary are updated and available.
+	CanaryReady bool
+	// CanaryFailed is set when the canary didn't become ready within its progress deadline.
+	CanaryFailed bool
+	// StableUpdated is set when the stable Deployment runs the new version with all replicas available.
+	StableUpdated bool
+}
+
+// Observe reads the progress of the rollout of a deployment from the canary and stable Deployments.
+// A canary left over from an earlier deployment is ignored, so that a new deployment starts over.
+func Observe(canary, stable *appsv1.Deployment, correlationID string) Observed {
+	observed := Observed{}
+	if canary == nil || canary.GetAnnotations()[annotations.DeploymentCorrelationID] != correlationID {
+		return observed
+	}
+
+	canaryAnnotations := canary.GetAnnotations()
+	observed.Phase = Phase(canaryAnnotations[PhaseAnnotation])
+	observed.Step, _ = strconv.Atoi(canaryAnnotations[StepAnnotation])
+	observed.StepStarted, _ = time.Parse(time.RFC3339, canaryAnnotations[StepStartedAnnotation])
+	observed.CanaryReady = Complete(canary)
+	observed.CanaryFailed = progressDeadlineExceeded(canary)
+	observed.StableUpdated = stable != nil &&
+		stable.GetAnnotations()[annotations.DeploymentCorrelationID] == correlationID &&
+		Complete(stable)
+
+	return observed
+}
+
+// Complete returns true when all desired replicas of the Deployment are updated and available, and no old pods are running.
+func Complete(deployment *appsv1.Deployment) bool {
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	return deployment.Status.UpdatedReplicas == replicas &&
+		deployment.Status.Replicas == replicas &&
+		deployment.Status.AvailableReplicas == replicas &&
+		deployment.Status.ObservedGeneration >= deployment.Generation
+}
+
+func progressDeadlineExceeded(deployment *appsv1.Deployment) bool {
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing && condition.Reason == "ProgressDeadlineExceeded" {
+			return true
+		}
+	}
+	return false
+}
+
+// State is the next step of the rollout.
+type State struct {
+	Phase       Phase
+	Step        int
+	StepStarted time.Time
+	// Weight is the percentage of ingress traffic sent to the canary.
+	Weight  int
+	Message string
+	// RequeueAfter is when the rollout should be checked again. It is zero when the rollout has ended.
+	RequeueAfter time.Duration
+	// Changed is set when the phase or step differs from the observed one. The rollout is checked every
+	// CheckInterval, and only changes are worth reporting.
+	Changed bool
+}
+
+// Next decides the next step of the rollout.
+// Errors from the analysis are treated as inconclusive, and the analysis is retried.
+func Next(ctx context.Context, settings Settings, observed Observed, now time.Time, analyse Analysis) State {
+	state := next(ctx, settings, observed, now, analyse)
+	state.Changed = state.Phase != observed.Phase || state.Step != observed.Step
+	return state
+}
+
+func next(ctx context.Context, settings Settings, observed Observed, now time.Time, analyse Analysis) State {
+	switch observed.Phase {
+	case PhasePromoted, PhaseRolledBack:
+		return State{Phase: observed.Phase, Message: observed.Message}
+
+	case PhasePromoting:
+		if observed.StableUpdated {
+			return State{Phase: PhasePromoted, Message: "New version promoted; canary removed"}
+		}
+		return promoting()
+
+	case PhaseProgressing:
+		break
+
+	default:
+		return settings.progressing(0, now, CheckInterval, "waiting for canary to become ready")
+	}
+
+	step := min(observed.Step, len(settings.Steps)-1)
+
+	if observed.CanaryFailed {
+		return State{
+			Phase:   PhaseRolledBack,
+			Message: fmt.Sprintf("Canary did not become ready at step %d of %d; rolled back", step+1, len(settings.Steps)),
+		}
+	}
+
+	if !observed.CanaryReady {
+		return settings.progressing(step, observed.StepStarted, CheckInterval, "waiting for canary to become ready")
+	}
+
+	remaining := observed.StepStarted.Add(settings.StepInterval).Sub(now)
+	if remaining > 0 {
+		return settings.progressing(step, observed.StepStarted, remaining, "canary is ready")
+	}
+
+	if len(settings.Query) > 0 {
+		healthy, err := analyse(ctx, settings.Query)
+		if err != nil {
+			return settings.progressing(step, observed.StepStarted, CheckInterval, fmt.Sprintf("analysis inconclusive, retrying: %s", err))
+		}
+		if !healthy {
+			return State{
+				Phase:   PhaseRolledBack,
+				Message: fmt.Sprintf("Canary analysis failed at step %d of %d; rolled back", step+1, len(settings.Steps)),
+			}
+		}
+	}
+
+	if step+1 < len(settings.Steps) {
+		return settings.progressing(step+1, now, CheckInterval, "waiting for canary to become ready")
+	}
+
+	return promoting()
+}
+
+// Abort ends the rollout when the application enters maintenance mode.
+// A canary in progress is rolled back, so that the new version isn't promoted without analysis once maintenance ends.
+// A canary being promoted has passed its analysis, and the rollout is completed.
+// It returns false if the rollout hasn't started, in which case the new version is deployed as usual.
+func Abort(observed Observed) (State, bool) {
+	state := State{Phase: observed.Phase, Message: observed.Message}
+
+	switch observed.Phase {
+	case PhasePromoted, PhaseRolledBack:
+		break
+	case PhasePromoting:
+		state = State{Phase: PhasePromoted, Message: "New version promoted; canary removed"}
+	case PhaseProgressing:
+		state = State{Phase: PhaseRolledBack, Message: "Application entered maintenance mode during the rollout; rolled back"}
+	default:
+		return State{}, false
+	}
+
+	state.Changed = state.Phase != observed.Phase
+	return state, true
+}
+
+func promoting() State {
+	return State{
+		Phase:        PhasePromoting,
+		Weight:       100,
+		Message:      "All ingress traffic to the canary while the stable Deployment is updated",
+		RequeueAfter: CheckInterval,
+	}
+}
+
+func (s Settings) progressing(step int, started time.Time, requeueAfter time.Duration, status string) State {
+	return State{
+		Phase:        PhaseProgressing,
+		Step:         step,
+		StepStarted:  started,
+		Weight:       s.Steps[step],
+		Message:      fmt.Sprintf("Step %d of %d: %d%% of ingress traffic to the canary; %s", step+1, len(s.Steps), s.Steps[step], status),
+		RequeueAfter: requeueAfter,
+	}
+}
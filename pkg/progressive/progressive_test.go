@@ -0,0 +1,254 @@
+package progressive_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/nais/naiserator/pkg/annotations"
+	"github.com/nais/naiserator/pkg/naiserator/config"
+	"github.com/nais/naiserator/pkg/progressive"
+)
+
+var now = time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+func analysis(healthy bool, err error) progressive.Analysis {
+	return func(ctx context.Context, query string) (bool, error) {
+		return healthy, err
+	}
+}
+
+func TestNewSettings(t *testing.T) {
+	cfg := config.ProgressiveDelivery{Steps: []int{10, 50}, StepInterval: 5 * time.Minute}
+
+	settings, err := progressive.NewSettings(map[string]string{
+		annotations.DeliveryStrategy: annotations.DeliveryStrategyCanary,
+	}, cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{10, 50}, settings.Steps)
+	assert.Equal(t, 5*time.Minute, settings.StepInterval)
+
+	settings, err = progressive.NewSettings(map[string]string{
+		annotations.DeliveryStrategy:   annotations.DeliveryStrategyCanary,
+		annotations.CanarySteps:        "5,20,80",
+		annotations.CanaryStepInterval: "10m",
+	}, cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{5, 20, 80}, settings.Steps)
+	assert.Equal(t, 10*time.Minute, settings.StepInterval)
+
+	settings, err = progressive.NewSettings(map[string]string{
+		annotations.DeliveryStrategy: annotations.DeliveryStrategyBlueGreen,
+	}, cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0}, settings.Steps)
+
+	_, err = progressive.NewSettings(map[string]string{
+		annotations.DeliveryStrategy:    annotations.DeliveryStrategyCanary,
+		annotations.CanaryAnalysisQuery: "up",
+	}, cfg)
+	assert.ErrorContains(t, err, "no Prometheus is configured for progressive delivery in cluster")
+}
+
+func TestNext(t *testing.T) {
+	settings := progressive.Settings{
+		Strategy:     annotations.DeliveryStrategyCanary,
+		Steps:        []int{10, 50},
+		StepInterval: 5 * time.Minute,
+		Query:        "up",
+	}
+	started := now.Add(-10 * time.Minute)
+
+	testCases := []struct {
+		name     string
+		observed progressive.Observed
+		analysis progressive.Analysis
+		phase    progressive.Phase
+		step     int
+		weight   int
+		requeue  time.Duration
+		changed  bool
+	}{
+		{
+			name:     "new deployment starts at the first step",
+			observed: progressive.Observed{},
+			phase:    progressive.PhaseProgressing,
+			weight:   10,
+			requeue:  progressive.CheckInterval,
+			changed:  true,
+		},
+		{
+			name:     "step lasts until the canary is ready",
+			observed: progressive.Observed{Phase: progressive.PhaseProgressing, StepStarted: started},
+			phase:    progressive.PhaseProgressing,
+			weight:   10,
+			requeue:  progressive.CheckInterval,
+		},
+		{
+			name:     "step lasts for the step interval",
+			observed: progressive.Observed{Phase: progressive.PhaseProgressing, StepStarted: now.Add(-time.Minute), CanaryReady: true},
+			phase:    progressive.PhaseProgressing,
+			weight:   10,
+			requeue:  4 * time.Minute,
+		},
+		{
+			name:     "healthy canary moves on to the next step",
+			observed: progressive.Observed{Phase: progressive.PhaseProgressing, StepStarted: started, CanaryReady: true},
+			analysis: analysis(true, nil),
+			phase:    progressive.PhaseProgressing,
+			step:     1,
+			weight:   50,
+			requeue:  progressive.CheckInterval,
+			changed:  true,
+		},
+		{
+			name:     "healthy canary at the last step is promoted",
+			observed: progressive.Observed{Phase: progressive.PhaseProgressing, Step: 1, StepStarted: started, CanaryReady: true},
+			analysis: analysis(true, nil),
+			phase:    progressive.PhasePromoting,
+			weight:   100,
+			requeue:  progressive.CheckInterval,
+			changed:  true,
+		},
+		{
+			name:     "failing analysis rolls back",
+			observed: progressive.Observed{Phase: progressive.PhaseProgressing, StepStarted: started, CanaryReady: true},
+			analysis: analysis(false, nil),
+			phase:    progressive.PhaseRolledBack,
+			changed:  true,
+		},
+		{
+			name:     "inconclusive analysis is retried",
+			observed: progressive.Observed{Phase: progressive.PhaseProgressing, StepStarted: started, CanaryReady: true},
+			analysis: analysis(false, fmt.Errorf("connection refused")),
+			phase:    progressive.PhaseProgressing,
+			weight:   10,
+			requeue:  progressive.CheckInterval,
+		},
+		{
+			name:     "canary that doesn't become ready rolls back",
+			observed: progressive.Observed{Phase: progressive.PhaseProgressing, StepStarted: started, CanaryFailed: true},
+			phase:    progressive.PhaseRolledBack,
+			changed:  true,
+		},
+		{
+			name:     "promotion lasts until the stable deployment is updated",
+			observed: progressive.Observed{Phase: progressive.PhasePromoting, CanaryReady: true},
+			phase:    progressive.PhasePromoting,
+			weight:   100,
+			requeue:  progressive.CheckInterval,
+		},
+		{
+			name:     "promotion ends when the stable deployment is updated",
+			observed: progressive.Observed{Phase: progressive.PhasePromoting, StableUpdated: true},
+			phase:    progressive.PhasePromoted,
+			changed:  true,
+		},
+		{
+			name:     "rolled back deployment stays rolled back",
+			observed: progressive.Observed{Phase: progressive.PhaseRolledBack, Message: "rolled back"},
+			phase:    progressive.PhaseRolledBack,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			state := progressive.Next(context.Background(), settings, tc.observed, now, tc.analysis)
+			assert.Equal(t, tc.phase, state.Phase)
+			assert.Equal(t, tc.step, state.Step)
+			assert.Equal(t, tc.weight, state.Weight)
+			assert.Equal(t, tc.requeue, state.RequeueAfter)
+			assert.Equal(t, tc.changed, state.Changed)
+			assert.NotEmpty(t, state.Message)
+		})
+	}
+}
+
+func TestObserve(t *testing.T) {
+	canary := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				annotations.DeploymentCorrelationID: "new",
+				progressive.PhaseAnnotation:         string(progressive.PhaseProgressing),
+				progressive.StepAnnotation:          "1",
+				progressive.StepStartedAnnotation:   now.Format(time.RFC3339),
+			},
+		},
+		Spec: appsv1.DeploymentSpec{Replicas: new(int32(1))},
+		Status: appsv1.DeploymentStatus{
+			Replicas:          1,
+			UpdatedReplicas:   1,
+			AvailableReplicas: 1,
+		},
+	}
+	stable := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{annotations.DeploymentCorrelationID: "old"},
+		},
+	}
+
+	assert.Equal(t, progressive.Observed{
+		Phase:       progressive.PhaseProgressing,
+		Step:        1,
+		StepStarted: now,
+		CanaryReady: true,
+	}, progressive.Observe(canary, stable, "new"))
+
+	assert.Equal(t, progressive.Observed{}, progressive.Observe(canary, stable, "newer"))
+	assert.Equal(t, progressive.Observed{}, progressive.Observe(nil, stable, "new"))
+}
+
+func TestAbort(t *testing.T) {
+	testCases := []struct {
+		name     string
+		observed progressive.Observed
+		phase    progressive.Phase
+		changed  bool
+		aborted  bool
+	}{
+		{
+			name:     "rollout that hasn't started is left alone",
+			observed: progressive.Observed{},
+		},
+		{
+			name:     "canary in progress is rolled back",
+			observed: progressive.Observed{Phase: progressive.PhaseProgressing, Step: 1, CanaryReady: true},
+			phase:    progressive.PhaseRolledBack,
+			changed:  true,
+			aborted:  true,
+		},
+		{
+			name:     "canary being promoted is completed",
+			observed: progressive.Observed{Phase: progressive.PhasePromoting},
+			phase:    progressive.PhasePromoted,
+			changed:  true,
+			aborted:  true,
+		},
+		{
+			name:     "rolled back rollout stays rolled back",
+			observed: progressive.Observed{Phase: progressive.PhaseRolledBack, Message: "Canary analysis failed"},
+			phase:    progressive.PhaseRolledBack,
+			aborted:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			state, aborted := progressive.Abort(tc.observed)
+			assert.Equal(t, tc.aborted, aborted)
+			if !aborted {
+				return
+			}
+			assert.Equal(t, tc.phase, state.Phase)
+			assert.Equal(t, tc.changed, state.Changed)
+			assert.Zero(t, state.Weight)
+			assert.Zero(t, state.RequeueAfter)
+			assert.NotEmpty(t, state.Message)
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package progressive
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/api"
+	prometheusv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+// PrometheusAnalysis runs analysis queries against the Prometheus server at the given address.
+// Queries are comparisons that filter out unhealthy results, such as `sum(rate(http_errors{app="myapp"}[5m])) < 0.01`,
+// and the canary is healthy when the query returns at least one sample, whatever its value.
+func PrometheusAnalysis(address string) (Analysis, error) {
+	client, err := api.NewClient(api.Config{Address: address})
+	if err != nil {
+		return nil, fmt.Errorf("create prometheus client: %w", err)
+	}
+	prometheus := prometheusv1.NewAPI(client)
+
+	return func(ctx context.Context, query string) (bool, error) {
+		value, _, err := prometheus.Query(ctx, query, time.Now())
+		if err != nil {
+			return false, fmt.Errorf("query prometheus: %w", err)
+		}
+		return healthy(value)
+	}, nil
+}
+
+func healthy(value model.Value) (bool, error) {
+	vector, ok := value.(model.Vector)
+	if !ok {
+		return false, fmt.Errorf("query must return an instant vector, not %s", value.Type())
+	}
+	return len(vector) > 0, nil
+}
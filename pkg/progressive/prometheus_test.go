@@ -0,0 +1,57 @@
+package progressive_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nais/naiserator/pkg/progressive"
+)
+
+func TestPrometheusAnalysis(t *testing.T) {
+	// The query from the README, and what Prometheus returns for it when the canary's error ratio is 0, 0.005 and 0.2.
+	const query = `sum(rate(http_requests_total{app="myapp", track="canary", code=~"5.."}[5m]))
+  / sum(rate(http_requests_total{app="myapp", track="canary"}[5m])) < 0.01`
+	results := map[string]string{
+		"no errors":   `{"resultType":"vector","result":[{"metric":{},"value":[1760875200,"0"]}]}`,
+		"some errors": `{"resultType":"vector","result":[{"metric":{},"value":[1760875200,"0.005"]}]}`,
+		"failing":     `{"resultType":"vector","result":[]}`,
+		"scalar":      `{"resultType":"scalar","result":[1760875200,"0.5"]}`,
+		"matrix":      `{"resultType":"matrix","result":[]}`,
+	}
+	var scenario string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseForm()
+		assert.NoError(t, err)
+		assert.Equal(t, query, r.Form.Get("query"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":` + results[scenario] + `}`))
+	}))
+	defer server.Close()
+
+	analyse, err := progressive.PrometheusAnalysis(server.URL)
+	assert.NoError(t, err)
+
+	for _, test := range []struct {
+		scenario string
+		healthy  bool
+	}{
+		{scenario: "no errors", healthy: true},
+		{scenario: "some errors", healthy: true},
+		{scenario: "failing", healthy: false},
+	} {
+		scenario = test.scenario
+		healthy, err := analyse(context.Background(), query)
+		assert.NoError(t, err, test.scenario)
+		assert.Equal(t, test.healthy, healthy, test.scenario)
+	}
+
+	for _, scenario = range []string{"scalar", "matrix"} {
+		_, err = analyse(context.Background(), query)
+		assert.ErrorContains(t, err, "query must return an instant vector, not "+scenario)
+	}
+}
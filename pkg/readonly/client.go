@@ -2,12 +2,16 @@ package readonly
 
 import (
 	"context"
+	"time"
 
 	liberator_scheme "github.com/nais/liberator/pkg/scheme"
 	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -20,8 +24,17 @@ func NewClient(c client.Client) client.Client {
 	}
 }
 
+// Return a copy of `c` with write privileges dropped, where every intercepted write is stored in the recorder.
+func NewRecordingClient(c client.Client, recorder *Recorder) client.Client {
+	return &Client{
+		client:   c,
+		recorder: recorder,
+	}
+}
+
 type Client struct {
-	client client.Client
+	client   client.Client
+	recorder *Recorder
 }
 
 // Scheme returns the scheme this client is using.
@@ -54,21 +67,25 @@ func (c *Client) List(ctx context.Context, list client.ObjectList, opts ...clien
 
 func (c *Client) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
 	log.Debugf("Read-only client ignoring CREATE %s", liberator_scheme.TypeName(obj))
+	c.record(ctx, OperationCreate, obj)
 	return nil
 }
 
 func (c *Client) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
 	log.Debugf("Read-only client ignoring DELETE %s", liberator_scheme.TypeName(obj))
+	c.record(ctx, OperationDelete, obj)
 	return nil
 }
 
 func (c *Client) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
 	log.Debugf("Read-only client ignoring UPDATE %s", liberator_scheme.TypeName(obj))
+	c.record(ctx, OperationUpdate, obj)
 	return nil
 }
 
 func (c *Client) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
 	log.Debugf("Read-only client ignoring PATCH %s", liberator_scheme.TypeName(obj))
+	c.record(ctx, OperationPatch, obj)
 	return nil
 }
 
@@ -89,3 +106,73 @@ func (c *Client) Apply(ctx context.Context, obj runtime.ApplyConfiguration, opts
 	log.Debugf("Read-only client ignoring APPLY %T", obj)
 	return nil
 }
+
+func (c *Client) record(ctx context.Context, operation string, obj client.Object) {
+	if c.recorder == nil {
+		return
+	}
+
+	record := Record{
+		Time:      time.Now(),
+		Operation: operation,
+		Kind:      liberator_scheme.TypeName(obj),
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+		Object:    toUnstructured(obj),
+		Workload:  workload(obj),
+	}
+
+	live := c.live(ctx, obj)
+	if _, ok := obj.(*corev1.Secret); ok {
+		redactSecret(live, record.Object)
+	}
+
+	switch operation {
+	case OperationDelete:
+		record.Diff = diff(live, nil)
+	case OperationPatch:
+		// The patch is not applied, so the resulting object is unknown.
+	default:
+		record.Diff = diff(live, record.Object)
+	}
+
+	c.recorder.add(record)
+}
+
+// live fetches the current version of obj from the cluster, or nil if it doesn't exist.
+func (c *Client) live(ctx context.Context, obj client.Object) map[string]any {
+	gvk, err := c.client.GroupVersionKindFor(obj)
+	if err != nil {
+		return nil
+	}
+
+	existing, err := c.client.Scheme().New(gvk)
+	if err != nil {
+		return nil
+	}
+
+	existingObj, ok := existing.(client.Object)
+	if !ok {
+		return nil
+	}
+
+	err = c.client.Get(ctx, client.ObjectKeyFromObject(obj), existingObj)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Warnf("Read-only client: get live %s for diff: %s", liberator_scheme.TypeName(obj), err)
+		}
+		return nil
+	}
+
+	return toUnstructured(existingObj)
+}
+
+// workload returns the Application or Naisjob owning obj, so that writes can be looked up per workload.
+func workload(obj client.Object) types.NamespacedName {
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.Kind == "Application" || ref.Kind == "Naisjob" {
+			return types.NamespacedName{Namespace: obj.GetNamespace(), Name: ref.Name}
+		}
+	}
+	return types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+}
@@ -0,0 +1,226 @@
+package readonly
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	OperationCreate = "CREATE"
+	OperationUpdate = "UPDATE"
+	OperationPatch  = "PATCH"
+	OperationDelete = "DELETE"
+
+	// DefaultRecorderCapacity is the number of writes kept by the dry-run recorder.
+	DefaultRecorderCapacity = 10000
+
+	// RecorderPath is where recorded writes are served, as /dryrun/<namespace>[/<name>].
+	RecorderPath = "/dryrun/"
+)
+
+// Values of Secrets are replaced with these, since recorded writes are served without authentication.
+const (
+	redacted        = "<redacted>"
+	redactedChanged = "<redacted; changed>"
+)
+
+// Server-populated fields that would otherwise show up in every diff.
+var ignoredMetadataFields = []string{"creationTimestamp", "generation", "managedFields", "resourceVersion", "uid"}
+
+// FieldChange is a single difference between the live object and the object that would have been written.
+type FieldChange struct {
+	Path    string `json:"path"`
+	Live    any    `json:"live,omitempty"`
+	Desired any    `json:"desired,omitempty"`
+}
+
+// Record is a write intercepted by the read-only client.
+type Record struct {
+	Time      time.Time      `json:"time"`
+	Operation string         `json:"operation"`
+	Kind      string         `json:"kind"`
+	Namespace string         `json:"namespace"`
+	Name      string         `json:"name"`
+	Object    map[string]any `json:"object,omitempty"`
+	Diff      []FieldChange  `json:"diff,omitempty"`
+	// Workload is the Application or Naisjob that owns the object, or the object itself if it has no owner.
+	Workload types.NamespacedName `json:"-"`
+}
+
+// Recorder keeps the most recent intercepted writes, up to a fixed capacity.
+type Recorder struct {
+	lock     sync.RWMutex
+	records  []Record
+	next     int
+	capacity int
+}
+
+func NewRecorder(capacity int) *Recorder {
+	return &Recorder{
+		records:  make([]Record, 0, capacity),
+		capacity: capacity,
+	}
+}
+
+func (r *Recorder) add(record Record) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if len(r.records) < r.capacity {
+		r.records = append(r.records, record)
+		return
+	}
+	r.records[r.next] = record
+	r.next = (r.next + 1) % r.capacity
+}
+
+// Records returns the recorded writes for all workloads in the namespace, oldest first.
+// If name is set, only writes for that workload are returned.
+func (r *Recorder) Records(namespace, name string) []Record {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	result := make([]Record, 0)
+	for i := range r.records {
+		record := r.records[(r.next+i)%len(r.records)]
+		if record.Workload.Namespace != namespace {
+			continue
+		}
+		if len(name) > 0 && record.Workload.Name != name {
+			continue
+		}
+		result = append(result, record)
+	}
+
+	return result
+}
+
+// ServeHTTP serves the recorded writes for a namespace or a single workload as JSON.
+func (r *Recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(req.URL.Path, RecorderPath), "/"), "/")
+	if len(parts[0]) == 0 || len(parts) > 2 {
+		http.Error(w, fmt.Sprintf("usage: %s<namespace>[/<name>]", RecorderPath), http.StatusNotFound)
+		return
+	}
+
+	name := ""
+	if len(parts) == 2 {
+		name = parts[1]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(r.Records(parts[0], name))
+}
+
+func toUnstructured(obj runtime.Object) map[string]any {
+	if obj == nil {
+		return nil
+	}
+	if value := reflect.ValueOf(obj); value.Kind() == reflect.Pointer && value.IsNil() {
+		return nil
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+
+	delete(content, "status")
+	if metadata, ok := content["metadata"].(map[string]any); ok {
+		for _, field := range ignoredMetadataFields {
+			delete(metadata, field)
+		}
+	}
+
+	return content
+}
+
+// redactSecret replaces the values of a live and a desired Secret, so that the diff only shows which keys
+// would be added, changed or removed. Either object may be nil.
+func redactSecret(live, desired map[string]any) {
+	normalizeSecret(desired)
+	normalizeSecret(live)
+
+	liveData, _ := live["data"].(map[string]any)
+	desiredData, _ := desired["data"].(map[string]any)
+	for key, value := range desiredData {
+		if liveValue, found := liveData[key]; found && !reflect.DeepEqual(liveValue, value) {
+			desiredData[key] = redactedChanged
+		} else {
+			desiredData[key] = redacted
+		}
+	}
+	for key := range liveData {
+		liveData[key] = redacted
+	}
+}
+
+// normalizeSecret merges stringData into data, as the API server does when the Secret is written.
+func normalizeSecret(content map[string]any) {
+	stringData, ok := content["stringData"].(map[string]any)
+	if !ok {
+		return
+	}
+
+	data, ok := content["data"].(map[string]any)
+	if !ok {
+		data = make(map[string]any)
+		content["data"] = data
+	}
+	for key, value := range stringData {
+		str, _ := value.(string)
+		data[key] = base64.StdEncoding.EncodeToString([]byte(str))
+	}
+	delete(content, "stringData")
+}
+
+// diff returns the changes between live and desired, sorted by path.
+// Lists are compared as a whole.
+func diff(live, desired map[string]any) []FieldChange {
+	changes := make([]FieldChange, 0)
+	diffValues("", live, desired, &changes)
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+func diffValues(path string, live, desired any, changes *[]FieldChange) {
+	liveMap, liveIsMap := live.(map[string]any)
+	desiredMap, desiredIsMap := desired.(map[string]any)
+
+	if liveIsMap && desiredIsMap {
+		for key, value := range liveMap {
+			diffValues(path+"."+key, value, desiredMap[key], changes)
+		}
+		for key, value := range desiredMap {
+			if _, found := liveMap[key]; !found {
+				diffValues(path+"."+key, nil, value, changes)
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(live, desired) {
+		if len(path) == 0 {
+			path = "."
+		}
+		*changes = append(*changes, FieldChange{
+			Path:    path,
+			Live:    live,
+			Desired: desired,
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package readonly
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestRecorder(t *testing.T) {
+	recorder := NewRecorder(2)
+	for _, name := range []string{"first", "second", "third"} {
+		recorder.add(Record{
+			Operation: OperationCreate,
+			Name:      name,
+			Workload:  types.NamespacedName{Namespace: "team", Name: "app"},
+		})
+	}
+	recorder.add(Record{Operation: OperationCreate, Workload: types.NamespacedName{Namespace: "other", Name: "app"}})
+
+	records := recorder.Records("team", "app")
+	if assert.Len(t, records, 1, "oldest records are evicted when full") {
+		assert.Equal(t, "third", records[0].Name)
+	}
+	assert.Len(t, recorder.Records("other", ""), 1)
+	assert.Empty(t, recorder.Records("team", "missing"))
+
+	response := httptest.NewRecorder()
+	recorder.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/dryrun/team/app", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	served := make([]Record, 0)
+	assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &served))
+	assert.Len(t, served, 1)
+
+	response = httptest.NewRecorder()
+	recorder.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/dryrun/", nil))
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestDiff(t *testing.T) {
+	live := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "app",
+			ResourceVersion: "42",
+			Labels:          map[string]string{"app": "app", "team": "team"},
+		},
+		Data: map[string]string{"removed": "value", "changed": "before"},
+	}
+	desired := live.DeepCopy()
+	desired.ResourceVersion = ""
+	desired.Labels["team"] = "other"
+	desired.Data = map[string]string{"changed": "after", "added": "value"}
+
+	assert.Equal(t, []FieldChange{
+		{Path: ".data.added", Desired: "value"},
+		{Path: ".data.changed", Live: "before", Desired: "after"},
+		{Path: ".data.removed", Live: "value"},
+		{Path: ".metadata.labels.team", Live: "team", Desired: "other"},
+	}, diff(toUnstructured(live), toUnstructured(desired)))
+
+	assert.Empty(t, diff(toUnstructured(live), toUnstructured(live)))
+}
+
+func TestRedactSecret(t *testing.T) {
+	live := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "app"},
+		Data: map[string][]byte{
+			"unchanged": []byte("password"),
+			"changed":   []byte("before"),
+			"removed":   []byte("value"),
+		},
+	}
+	desired := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "app"},
+		StringData: map[string]string{
+			"unchanged": "password",
+			"changed":   "after",
+			"added":     "value",
+		},
+	}
+
+	liveContent, desiredContent := toUnstructured(live), toUnstructured(desired)
+	redactSecret(liveContent, desiredContent)
+
+	assert.Equal(t, []FieldChange{
+		{Path: ".data.added", Desired: redacted},
+		{Path: ".data.changed", Live: redacted, Desired: redactedChanged},
+		{Path: ".data.removed", Live: redacted},
+	}, diff(liveContent, desiredContent))
+
+	encoded, err := json.Marshal(desiredContent)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(encoded), "password")
+	assert.NotContains(t, string(encoded), "after")
+
+	// Secrets that are created have no live side.
+	desiredContent = toUnstructured(desired)
+	redactSecret(nil, desiredContent)
+	encoded, err = json.Marshal(desiredContent)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(encoded), "password")
+}
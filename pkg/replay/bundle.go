@@ -0,0 +1,243 @@
+// Package replay records everything the generators read while preparing a rollout,
+// so that the generated resources can be reproduced offline.
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/nais/naiserator/pkg/annotations"
+	"github.com/nais/naiserator/pkg/naiserator/config"
+	"github.com/nais/naiserator/pkg/resourcecreator/resource"
+)
+
+// RecordAnnotation set to "true" on a workload records a bundle on every rollout, if recording is enabled in the cluster.
+const RecordAnnotation = annotations.Record
+
+// Read is a single object or list returned to the generator from the cluster.
+type Read struct {
+	APIVersion string          `json:"apiVersion"`
+	Kind       string          `json:"kind"`
+	Namespace  string          `json:"namespace,omitempty"`
+	Name       string          `json:"name,omitempty"`
+	NotFound   bool            `json:"notFound,omitempty"`
+	Object     json.RawMessage `json:"object,omitempty"`
+}
+
+// Bundle holds the inputs to the generator for a single rollout.
+type Bundle struct {
+	Time          time.Time       `json:"time"`
+	CorrelationID string          `json:"correlationID"`
+	APIVersion    string          `json:"apiVersion"`
+	Kind          string          `json:"kind"`
+	Source        json.RawMessage `json:"source"`
+	Config        config.Config   `json:"config"`
+	Reads         []Read          `json:"reads"`
+
+	lock sync.Mutex
+}
+
+// NewBundle captures the source, with defaults and effective image applied, and the configuration used by the generator.
+func NewBundle(source resource.Source, cfg config.Config) (*Bundle, error) {
+	data, err := json.Marshal(source)
+	if err != nil {
+		return nil, fmt.Errorf("encode source: %w", err)
+	}
+
+	gvk := source.GetObjectKind().GroupVersionKind()
+
+	return &Bundle{
+		Time:          time.Now(),
+		CorrelationID: source.CorrelationID(),
+		APIVersion:    gvk.GroupVersion().String(),
+		Kind:          gvk.Kind,
+		Source:        data,
+		Config:        cfg,
+		Reads:         make([]Read, 0),
+	}, nil
+}
+
+func Load(path string) (*Bundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := &Bundle{}
+	err = json.Unmarshal(data, bundle)
+	if err != nil {
+		return nil, fmt.Errorf("decode bundle: %w", err)
+	}
+
+	return bundle, nil
+}
+
+// Save writes the bundle to a file in dir, and returns the file name.
+func (b *Bundle) Save(dir string, source resource.Source) (string, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("%s-%s-%s-%s.json", strings.ToLower(b.Kind), source.GetNamespace(), source.GetName(), b.Time.UTC().Format("20060102T150405Z"))
+	path := filepath.Join(dir, name)
+
+	return path, os.WriteFile(path, data, 0o644)
+}
+
+func (b *Bundle) record(read Read) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.Reads = append(b.Reads, read)
+}
+
+// DecodeSource returns the recorded source as its concrete type.
+func (b *Bundle) DecodeSource(scheme *runtime.Scheme) (resource.Source, error) {
+	obj, err := scheme.New(schema.FromAPIVersionAndKind(b.APIVersion, b.Kind))
+	if err != nil {
+		return nil, err
+	}
+
+	source, ok := obj.(resource.Source)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a workload", b.Kind)
+	}
+
+	err = json.Unmarshal(b.Source, source)
+	if err != nil {
+		return nil, fmt.Errorf("decode source: %w", err)
+	}
+	source.GetObjectKind().SetGroupVersionKind(schema.FromAPIVersionAndKind(b.APIVersion, b.Kind))
+
+	return source, nil
+}
+
+// Client returns a client that answers reads with the recorded objects.
+func (b *Bundle) Client(scheme *runtime.Scheme) (client.Client, error) {
+	objects := make(map[string]client.Object)
+
+	add := func(obj client.Object) {
+		gvk := obj.GetObjectKind().GroupVersionKind()
+		objects[gvk.String()+"/"+obj.GetNamespace()+"/"+obj.GetName()] = obj
+	}
+
+	for _, read := range b.Reads {
+		if read.NotFound {
+			continue
+		}
+
+		gvk := schema.FromAPIVersionAndKind(read.APIVersion, read.Kind)
+		obj, err := scheme.New(gvk)
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal(read.Object, obj)
+		if err != nil {
+			return nil, fmt.Errorf("decode %s: %w", read.Kind, err)
+		}
+
+		if !meta.IsListType(obj) {
+			obj.GetObjectKind().SetGroupVersionKind(gvk)
+			add(obj.(client.Object))
+			continue
+		}
+
+		items, err := meta.ExtractList(obj)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			item.GetObjectKind().SetGroupVersionKind(gvk.GroupVersion().WithKind(strings.TrimSuffix(gvk.Kind, "List")))
+			add(item.(client.Object))
+		}
+	}
+
+	builder := fake.NewClientBuilder().WithScheme(scheme)
+	for _, obj := range objects {
+		builder = builder.WithObjects(obj)
+	}
+
+	return builder.Build(), nil
+}
+
+// RecordingClient records every object read through it into the bundle.
+func (b *Bundle) RecordingClient(c client.Client) client.Client {
+	return &recordingClient{
+		Client: c,
+		bundle: b,
+	}
+}
+
+type recordingClient struct {
+	client.Client
+	bundle *Bundle
+}
+
+func (c *recordingClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	err := c.Client.Get(ctx, key, obj, opts...)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	read, recordErr := c.read(obj, errors.IsNotFound(err))
+	if recordErr == nil {
+		read.Namespace = key.Namespace
+		read.Name = key.Name
+		c.bundle.record(read)
+	}
+
+	return err
+}
+
+func (c *recordingClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	err := c.Client.List(ctx, list, opts...)
+	if err != nil {
+		return err
+	}
+
+	read, recordErr := c.read(list, false)
+	if recordErr == nil {
+		c.bundle.record(read)
+	}
+
+	return nil
+}
+
+func (c *recordingClient) read(obj runtime.Object, notFound bool) (Read, error) {
+	gvk, err := c.GroupVersionKindFor(obj)
+	if err != nil {
+		return Read{}, err
+	}
+
+	read := Read{
+		APIVersion: gvk.GroupVersion().String(),
+		Kind:       gvk.Kind,
+		NotFound:   notFound,
+	}
+
+	if !notFound {
+		read.Object, err = json.Marshal(obj)
+		if err != nil {
+			return Read{}, err
+		}
+	}
+
+	return read, nil
+}
@@ -0,0 +1,53 @@
+package replay
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestBundle_RecordAndReplay(t *testing.T) {
+	ctx := context.Background()
+	namespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "team",
+			Annotations: map[string]string{"cnrm.cloud.google.com/project-id": "team-project"},
+		},
+	}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team", Labels: map[string]string{"app": "app"}},
+	}
+	live := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(namespace, deployment).Build()
+
+	bundle := &Bundle{}
+	recording := bundle.RecordingClient(live)
+
+	assert.NoError(t, recording.Get(ctx, client.ObjectKey{Name: "team"}, &corev1.Namespace{}))
+	err := recording.Get(ctx, client.ObjectKey{Name: "missing", Namespace: "team"}, &corev1.Secret{})
+	assert.True(t, errors.IsNotFound(err))
+	assert.NoError(t, recording.List(ctx, &appsv1.DeploymentList{}, client.InNamespace("team")))
+	assert.Len(t, bundle.Reads, 3)
+	assert.True(t, bundle.Reads[1].NotFound)
+
+	replayed, err := bundle.Client(scheme.Scheme)
+	assert.NoError(t, err)
+
+	ns := &corev1.Namespace{}
+	assert.NoError(t, replayed.Get(ctx, client.ObjectKey{Name: "team"}, ns))
+	assert.Equal(t, "team-project", ns.Annotations["cnrm.cloud.google.com/project-id"])
+
+	err = replayed.Get(ctx, client.ObjectKey{Name: "missing", Namespace: "team"}, &corev1.Secret{})
+	assert.True(t, errors.IsNotFound(err))
+
+	deployments := &appsv1.DeploymentList{}
+	assert.NoError(t, replayed.List(ctx, deployments, client.MatchingLabels{"app": "app"}))
+	assert.Len(t, deployments.Items, 1)
+}
@@ -0,0 +1,123 @@
+package canary
+
+import (
+	"fmt"
+	"maps"
+	"strconv"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/nais/naiserator/pkg/progressive"
+	"github.com/nais/naiserator/pkg/resourcecreator/deployment"
+	"github.com/nais/naiserator/pkg/resourcecreator/resource"
+	"github.com/nais/naiserator/pkg/resourcecreator/service"
+)
+
+type Source interface {
+	deployment.Source
+	service.Source
+}
+
+type Config interface {
+	deployment.Config
+	service.Config
+	GetProgressiveState() *progressive.State
+	GetStableDeployment() *appsv1.Deployment
+}
+
+// Name is the name of the Deployment running the new version of an application during a progressive rollout.
+func Name(source resource.Source) string {
+	return source.GetName() + "-" + progressive.TrackCanary
+}
+
+// Create generates the Deployments of an application that is rolled out progressively.
+// The stable Deployment keeps running the previous version until the canary is promoted;
+// the canary and the Services that split traffic between them only exist while the rollout is in progress.
+// The application Service, created by service.Create, selects the stable pods until the canary gets all the traffic.
+func Create(app Source, ast *resource.Ast, cfg Config) error {
+	stable, err := deployment.New(app, ast, cfg)
+	if err != nil {
+		return err
+	}
+	stable.Spec.Template.Labels[progressive.TrackLabel] = progressive.TrackStable
+
+	state := cfg.GetProgressiveState()
+	if state == nil || state.Phase == progressive.PhasePromoted {
+		ast.AppendOperation(resource.OperationCreateOrUpdate, stable)
+		return nil
+	}
+
+	live := cfg.GetStableDeployment()
+	if live == nil {
+		return fmt.Errorf("BUG: progressive rollout without a stable deployment")
+	}
+
+	switch state.Phase {
+	case progressive.PhaseRolledBack:
+		// Scaled like a regular Deployment, so that an application in maintenance mode is scaled down.
+		rolledBack := unchanged(app, live)
+		rolledBack.Spec.Replicas = new(cfg.GetNumReplicas())
+		ast.AppendOperation(resource.OperationCreateOrUpdate, rolledBack)
+		return nil
+	case progressive.PhaseProgressing:
+		ast.AppendOperation(resource.OperationCreateOrUpdate, unchanged(app, live))
+	case progressive.PhasePromoting:
+		ast.AppendOperation(resource.OperationCreateOrUpdate, stable)
+	}
+
+	ast.AppendOperation(resource.OperationCreateOrUpdate, canary(app, stable, *state, cfg.GetNumReplicas()))
+	service.CreateTrack(app, ast, cfg, progressive.TrackLabel, progressive.TrackStable)
+	service.CreateTrack(app, ast, cfg, progressive.TrackLabel, progressive.TrackCanary)
+	if state.Weight > 0 && state.Weight < 100 {
+		service.CreateSplit(app, ast, cfg)
+	}
+
+	return nil
+}
+
+// canary runs the new version, and records the progress of the rollout in its annotations.
+func canary(app Source, stable *appsv1.Deployment, state progressive.State, numReplicas int32) *appsv1.Deployment {
+	canary := stable.DeepCopy()
+	canary.Name = Name(app)
+	canary.Annotations[progressive.PhaseAnnotation] = string(state.Phase)
+	canary.Annotations[progressive.StepAnnotation] = strconv.Itoa(state.Step)
+	canary.Annotations[progressive.StepStartedAnnotation] = state.StepStarted.Format(time.RFC3339)
+
+	canary.Spec.Replicas = new(replicas(state.Weight, numReplicas))
+	canary.Spec.Selector = &metav1.LabelSelector{
+		MatchLabels: map[string]string{"app": app.GetName(), progressive.TrackLabel: progressive.TrackCanary},
+	}
+	canary.Spec.Template.Labels[progressive.TrackLabel] = progressive.TrackCanary
+
+	return canary
+}
+
+// replicas returns the number of canary replicas for a traffic weight.
+// The canary's share of the replicas matches the weight, so that traffic that isn't split by the ingress controller
+// is shared in about the same way. The canary runs at full size when it gets all or none of the ingress traffic,
+// the latter being a blue-green rollout.
+func replicas(weight int, numReplicas int32) int32 {
+	if weight <= 0 || weight >= 100 {
+		return numReplicas
+	}
+	canaryReplicas := (int(numReplicas)*weight + (100 - weight) - 1) / (100 - weight)
+	return int32(max(1, min(canaryReplicas, int(numReplicas))))
+}
+
+// unchanged returns the stable Deployment as it runs in the cluster, so that the previous version keeps running.
+func unchanged(app Source, live *appsv1.Deployment) *appsv1.Deployment {
+	objectMeta := resource.CreateObjectMeta(app)
+	// Keep the correlation ID of the deployment that the stable Deployment runs.
+	objectMeta.Annotations = maps.Clone(live.GetAnnotations())
+
+	return &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Deployment",
+			APIVersion: "apps/v1",
+		},
+		ObjectMeta: objectMeta,
+		Spec:       *live.Spec.DeepCopy(),
+	}
+}
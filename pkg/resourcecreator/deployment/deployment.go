@@ -40,10 +40,22 @@ type Config interface {
 }
 
 func Create(app Source, ast *resource.Ast, cfg Config) error {
+	deployment, err := New(app, ast, cfg)
+	if err != nil {
+		return err
+	}
+
+	ast.AppendOperation(resource.OperationCreateOrUpdate, deployment)
+
+	return nil
+}
+
+// New generates the Deployment of an application without adding it to the AST.
+func New(app Source, ast *resource.Ast, cfg Config) (*appsv1.Deployment, error) {
 	objectMeta := resource.CreateObjectMeta(app)
 	spec, err := deploymentSpec(app, ast, cfg)
 	if err != nil {
-		return fmt.Errorf("create deployment: %w", err)
+		return nil, fmt.Errorf("create deployment: %w", err)
 	}
 
 	if val, ok := app.GetAnnotations()["kubernetes.io/change-cause"]; ok {
@@ -61,9 +73,7 @@ func Create(app Source, ast *resource.Ast, cfg Config) error {
 		Spec:       *spec,
 	}
 
-	ast.AppendOperation(resource.OperationCreateOrUpdate, deployment)
-
-	return nil
+	return deployment, nil
 }
 
 func deploymentSpec(app Source, ast *resource.Ast, cfg Config) (*appsv1.DeploymentSpec, error) {
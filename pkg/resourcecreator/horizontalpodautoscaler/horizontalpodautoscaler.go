@@ -7,6 +7,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/nais/naiserator/pkg/resourcecreator/resource"
+	"github.com/nais/naiserator/pkg/resourcecreator/statefulset"
 )
 
 const (
@@ -43,10 +44,15 @@ func Create(source Source, ast *resource.Ast) {
 		metricSpecs = append(metricSpecs, createCpuMetricSpec(replicas.CpuThresholdPercentage))
 	}
 
+	targetKind := "Deployment"
+	if statefulset.Enabled(source) {
+		targetKind = "StatefulSet"
+	}
+
 	hpaSpec := v2.HorizontalPodAutoscalerSpec{
 		ScaleTargetRef: v2.CrossVersionObjectReference{
 			APIVersion: "apps/v1",
-			Kind:       "Deployment",
+			Kind:       targetKind,
 			Name:       source.GetName(),
 		},
 		Metrics:     metricSpecs,
@@ -4,16 +4,54 @@ import (
 	"fmt"
 	"net/url"
 	"regexp"
+	"slices"
 	"strings"
 )
 
-const haproxyBackendConfigAnnotation = "haproxy.org/backend-config-snippet"
+const (
+	haproxyBackendConfigAnnotation = "haproxy.org/backend-config-snippet"
+	nginxAnnotationPrefix          = "nginx.ingress.kubernetes.io/"
+)
 
 var (
 	nginxCaptureGroupRegex = regexp.MustCompile(`\$(\d+)`)
 	nginxArgVariableRegex  = regexp.MustCompile(`\$arg_(\w+)`)
+
+	// Mapping from nginx annotation short key to HAProxy equivalent.
+	haProxyAnnotations = map[string]string{
+		"keepalive-timeout":     "timeout-http-keep-alive",
+		"proxy-connect-timeout": "timeout-connect",
+		"proxy-read-timeout":    "timeout-server",
+		"proxy-send-timeout":    "timeout-client",
+		"upstream-vhost":        "set-host",
+	}
+
+	// nginx annotations that are translated into one or more HAProxy annotations by a dedicated function.
+	migratedNginxAnnotations = []string{
+		"limit-rpm",
+		"rewrite-target",
+		"whitelist-source-range",
+	}
 )
 
+// NginxOnlyAnnotations returns the sorted nginx annotation keys that have no HAProxy equivalent,
+// and are thus ignored when generating HAProxy ingresses.
+func NginxOnlyAnnotations(annotations map[string]string) []string {
+	keys := make([]string, 0)
+	for key := range annotations {
+		nginxKey, found := strings.CutPrefix(key, nginxAnnotationPrefix)
+		if !found {
+			continue
+		}
+		if _, mapped := haProxyAnnotations[nginxKey]; mapped || slices.Contains(migratedNginxAnnotations, nginxKey) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
 func copyHAProxyAnnotations(dst, src map[string]string) {
 	for k, v := range src {
 		if !strings.HasPrefix(k, "haproxy.org/") {
@@ -32,7 +70,7 @@ func copyHAProxyAnnotations(dst, src map[string]string) {
 
 func copyNginxAnnotations(dst, src map[string]string) {
 	for k, v := range src {
-		if strings.HasPrefix(k, "nginx.ingress.kubernetes.io/") {
+		if strings.HasPrefix(k, nginxAnnotationPrefix) {
 			dst[k] = v
 		}
 	}
@@ -42,17 +80,8 @@ func migrateNginxAnnotationsToHAProxyAnnotations(haProxy, nginx map[string]strin
 	nginxAnnotations := map[string]string{}
 	copyNginxAnnotations(nginxAnnotations, nginx)
 
-	// Mapping from nginx annotation short key to HAProxy equivalent.
-	haProxyAnnotations := map[string]string{
-		"keepalive-timeout":     "timeout-http-keep-alive",
-		"proxy-connect-timeout": "timeout-connect",
-		"proxy-read-timeout":    "timeout-server",
-		"proxy-send-timeout":    "timeout-client",
-		"upstream-vhost":        "set-host",
-	}
-
 	for key, value := range nginxAnnotations {
-		nginxKey, _ := strings.CutPrefix(key, "nginx.ingress.kubernetes.io/")
+		nginxKey, _ := strings.CutPrefix(key, nginxAnnotationPrefix)
 		haProxyKey, performMapping := haProxyAnnotations[nginxKey]
 		if !performMapping || haProxyKey == "" {
 			continue
@@ -0,0 +1,91 @@
+package ingress
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nais/liberator/pkg/namegen"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/nais/naiserator/pkg/progressive"
+	"github.com/nais/naiserator/pkg/resourcecreator/service"
+)
+
+const (
+	nginxCanaryAnnotation       = "nginx.ingress.kubernetes.io/canary"
+	nginxCanaryWeightAnnotation = "nginx.ingress.kubernetes.io/canary-weight"
+)
+
+// progressing returns the state of a progressive rollout that splits traffic between the stable and canary Deployments.
+func progressing(cfg Config) (*progressive.State, bool) {
+	state := cfg.GetProgressiveState()
+	return state, state != nil && !state.Phase.Done()
+}
+
+// backendServiceName returns the Service that the ingress rules of an application send traffic to.
+// In maintenance mode, that is the shared maintenance backend, if the cluster has one.
+//
+// During a progressive rollout, nginx sends traffic to the stable pods, and the canary's share of it
+// through a separate canary ingress. HAProxy can't split traffic between ingresses; it sends all traffic to the
+// stable or the canary pods at 0% and 100%, and to the pods of both tracks through the split Service in between.
+// HAProxy balances between pods, so the canary's share of the traffic only approximates the weight,
+// following the canary's share of the replicas.
+func backendServiceName(source Source, cfg Config, isHAProxy bool) string {
+	if maintenanceBackend(cfg) {
+		return service.MaintenanceName(source)
+	}
+
+	state, ok := progressing(cfg)
+	if !ok {
+		return source.GetName()
+	}
+
+	switch {
+	case !isHAProxy || state.Weight == 0:
+		return service.TrackName(source, progressive.TrackStable)
+	case state.Weight == 100:
+		return service.TrackName(source, progressive.TrackCanary)
+	default:
+		return service.SplitName(source)
+	}
+}
+
+// createCanaryIngresses creates an nginx canary ingress for each nginx ingress of the application,
+// sending the canary's share of the traffic to the canary pods.
+func createCanaryIngresses(source Source, cfg Config, ingresses map[string]*networkingv1.Ingress) ([]*networkingv1.Ingress, error) {
+	canaryIngresses := make([]*networkingv1.Ingress, 0)
+
+	state, ok := progressing(cfg)
+	if !ok {
+		return canaryIngresses, nil
+	}
+
+	for ingressClass, ingress := range ingresses {
+		if strings.HasSuffix(ingressClass, "haproxy") {
+			continue
+		}
+
+		name, err := namegen.ShortName(fmt.Sprintf("%s-%s-%s", source.GetName(), ingressClass, progressive.TrackCanary), validation.DNS1035LabelMaxLength)
+		if err != nil {
+			return nil, err
+		}
+
+		canary := ingress.DeepCopy()
+		canary.Name = name
+		canary.Annotations[nginxCanaryAnnotation] = "true"
+		canary.Annotations[nginxCanaryWeightAnnotation] = strconv.Itoa(state.Weight)
+
+		backend := ingressServiceBackend(service.TrackName(source, progressive.TrackCanary))
+		for _, rule := range canary.Spec.Rules {
+			for i := range rule.HTTP.Paths {
+				rule.HTTP.Paths[i].Backend = backend
+			}
+		}
+
+		canaryIngresses = append(canaryIngresses, canary)
+	}
+
+	return canaryIngresses, nil
+}
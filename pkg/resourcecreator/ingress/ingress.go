@@ -8,6 +8,7 @@ import (
 	nais_io_v1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
 	nais_io_v1alpha1 "github.com/nais/liberator/pkg/apis/nais.io/v1alpha1"
 	"github.com/nais/liberator/pkg/namegen"
+	"github.com/nais/naiserator/pkg/progressive"
 	"github.com/nais/naiserator/pkg/resourcecreator/resource"
 	"github.com/nais/naiserator/pkg/util"
 	networkingv1 "k8s.io/api/networking/v1"
@@ -31,12 +32,16 @@ type Config interface {
 	GetDocUrl() string
 	GetClusterName() string
 	IsHAProxyEnabled() bool
+	GetProgressiveState() *progressive.State
+	IsMaintenanceEnabled() bool
+	GetMaintenanceBackend() string
+	GetMaintenanceMessage() string
 }
 
-func ingressServiceBackend(appName string) networkingv1.IngressBackend {
+func ingressServiceBackend(serviceName string) networkingv1.IngressBackend {
 	return networkingv1.IngressBackend{
 		Service: &networkingv1.IngressServiceBackend{
-			Name: appName,
+			Name: serviceName,
 			Port: networkingv1.ServiceBackendPort{
 				Number: int32(nais_io_v1alpha1.DefaultServicePort),
 			},
@@ -52,8 +57,8 @@ func ingressPath(path string, pathType networkingv1.PathType, backend networking
 	}
 }
 
-func createIngressRule(appName string, u *url.URL, isHAProxy bool, annotations map[string]string) networkingv1.IngressRule {
-	backend := ingressServiceBackend(appName)
+func createIngressRule(serviceName string, u *url.URL, isHAProxy bool, annotations map[string]string) networkingv1.IngressRule {
+	backend := ingressServiceBackend(serviceName)
 
 	var paths []networkingv1.HTTPIngressPath
 	if isHAProxy {
@@ -162,6 +167,13 @@ func createIngressList(source Source, cfg Config) ([]*networkingv1.Ingress, erro
 		return nil, err
 	}
 
+	applyMaintenance(cfg, ingresses)
+
+	canaryIngresses, err := createCanaryIngresses(source, cfg, ingresses)
+	if err != nil {
+		return nil, err
+	}
+
 	redirectIngresses := make(map[string]*networkingv1.Ingress)
 	if hasRedirects(source) {
 		err := createRedirectIngresses(source, cfg, ingresses, redirectIngresses)
@@ -170,11 +182,13 @@ func createIngressList(source Source, cfg Config) ([]*networkingv1.Ingress, erro
 		}
 	}
 
-	ingressList := make([]*networkingv1.Ingress, 0, len(ingresses)+len(redirectIngresses))
+	ingressList := make([]*networkingv1.Ingress, 0, len(ingresses)+len(canaryIngresses)+len(redirectIngresses))
 	for _, ingress := range ingresses {
 		ingressList = append(ingressList, ingress)
 	}
 
+	ingressList = append(ingressList, canaryIngresses...)
+
 	for _, ingress := range redirectIngresses {
 		ingressList = append(ingressList, ingress)
 	}
@@ -229,7 +243,7 @@ func createIngresses(source Source, cfg Config) (map[string]*networkingv1.Ingres
 				ruleURL = &nginxURL
 			}
 
-			rule := createIngressRule(source.GetName(), ruleURL, isHAProxy, source.GetAnnotations())
+			rule := createIngressRule(backendServiceName(source, cfg, isHAProxy), ruleURL, isHAProxy, source.GetAnnotations())
 			ingress.Spec.Rules = append(ingress.Spec.Rules, rule)
 		}
 	}
@@ -250,5 +264,7 @@ func Create(source Source, ast *resource.Ast, cfg Config) error {
 	for _, ing := range ingresses {
 		ast.AppendOperation(resource.OperationCreateOrUpdate, ing)
 	}
+	createMaintenanceService(source, ast, cfg)
+
 	return nil
 }
@@ -56,3 +56,19 @@ func TestIngress(t *testing.T) {
 		}
 	})
 }
+
+func TestNginxOnlyAnnotations(t *testing.T) {
+	annotations := map[string]string{
+		"nginx.ingress.kubernetes.io/proxy-body-size":    "8m",
+		"nginx.ingress.kubernetes.io/proxy-read-timeout": "300",
+		"nginx.ingress.kubernetes.io/rewrite-target":     "/$1",
+		"nginx.ingress.kubernetes.io/affinity":           "cookie",
+		"haproxy.org/timeout-server":                     "300s",
+	}
+
+	assert.Equal(t, []string{
+		"nginx.ingress.kubernetes.io/affinity",
+		"nginx.ingress.kubernetes.io/proxy-body-size",
+	}, ingress.NginxOnlyAnnotations(annotations))
+	assert.Empty(t, ingress.NginxOnlyAnnotations(nil))
+}
@@ -0,0 +1,67 @@
+package ingress
+
+import (
+	"fmt"
+	"strings"
+
+	networkingv1 "k8s.io/api/networking/v1"
+
+	"github.com/nais/naiserator/pkg/annotations"
+	"github.com/nais/naiserator/pkg/resourcecreator/resource"
+	"github.com/nais/naiserator/pkg/resourcecreator/service"
+)
+
+const nginxCustomHTTPErrorsAnnotation = "nginx.ingress.kubernetes.io/custom-http-errors"
+
+var haproxyStringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", " ", "\r", " ")
+
+// maintenanceBackend returns true if the ingresses of an application in maintenance mode send traffic to the
+// shared maintenance backend. Otherwise, they keep sending traffic to the scaled down application, and the
+// ingress controller serves the maintenance message.
+func maintenanceBackend(cfg Config) bool {
+	return cfg.IsMaintenanceEnabled() && len(cfg.GetMaintenanceBackend()) > 0
+}
+
+// createMaintenanceService creates the Service that forwards to the shared maintenance backend, if it is used.
+func createMaintenanceService(source Source, ast *resource.Ast, cfg Config) {
+	if maintenanceBackend(cfg) {
+		service.CreateMaintenance(source, ast, cfg.GetMaintenanceBackend())
+	}
+}
+
+// applyMaintenance makes the ingresses of an application in maintenance mode serve a maintenance page.
+//
+// With a shared maintenance backend, the ingress rules already point at it, and nginx must talk plain HTTP to it.
+// Without one, nginx sends the 503 of the scaled down application to the controller's default backend,
+// which finds the message in the ingress annotations, and HAProxy returns the message itself.
+func applyMaintenance(cfg Config, ingresses map[string]*networkingv1.Ingress) {
+	if !cfg.IsMaintenanceEnabled() {
+		return
+	}
+
+	for ingressClass, ingress := range ingresses {
+		isHAProxy := strings.HasSuffix(ingressClass, "haproxy")
+
+		switch {
+		case maintenanceBackend(cfg):
+			if !isHAProxy {
+				ingress.Annotations["nginx.ingress.kubernetes.io/backend-protocol"] = "HTTP"
+			}
+		case isHAProxy:
+			snippet := fmt.Sprintf(
+				"http-request return status 503 content-type \"text/plain; charset=utf-8\" string \"%s\"",
+				haproxyStringEscaper.Replace(cfg.GetMaintenanceMessage()),
+			)
+			delimiter := "# Added by naiserator due to maintenance mode:"
+			snippet = fmt.Sprintf("###\n%s\n%s\n###", delimiter, snippet)
+			// Rules added earlier, such as source range restrictions, still apply.
+			if existing := ingress.Annotations[haproxyBackendConfigAnnotation]; existing != "" {
+				snippet = fmt.Sprintf("%s\n\n%s", existing, snippet)
+			}
+			ingress.Annotations[haproxyBackendConfigAnnotation] = snippet
+		default:
+			ingress.Annotations[nginxCustomHTTPErrorsAnnotation] = "503"
+			ingress.Annotations[annotations.MaintenanceMessage] = cfg.GetMaintenanceMessage()
+		}
+	}
+}
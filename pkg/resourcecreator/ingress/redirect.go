@@ -11,10 +11,12 @@ import (
 	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/util/validation"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nais/naiserator/pkg/annotations"
 )
 
 const (
-	AllowedRedirectAnnotation = "nais.io/allow-redirect"
+	AllowedRedirectAnnotation = annotations.AllowRedirect
 )
 
 func hasRedirects(source Source) bool {
@@ -52,7 +52,7 @@ func Create(source Source, ast *resource.Ast, cfg Config) error {
 		return fmt.Errorf("failed to build short name for role binding: %w", err)
 	}
 
-	ast.AppendOperation(resource.OperationCreateOrRecreate, roleBinding(appObjectMeta, roleBindingObjectMeta))
+	ast.AppendOperation(resource.OperationCreateOrUpdate, roleBinding(appObjectMeta, roleBindingObjectMeta))
 	ast.InitContainers = append(ast.InitContainers, container(source.GetName(), source.GetNamespace(), image))
 	ast.PrependEnv(electorEnv()...)
 	return nil
@@ -4,6 +4,7 @@ import (
 	"net/url"
 
 	nais_io_v1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -11,6 +12,9 @@ import (
 	"github.com/nais/naiserator/pkg/resourcecreator/resource"
 )
 
+// GateDNSPorts is the feature gate that restricts egress to the cluster DNS to the DNS port.
+const GateDNSPorts = "network-policy-dns-ports"
+
 type Source interface {
 	resource.Source
 	GetAccessPolicy() *nais_io_v1.AccessPolicy
@@ -27,6 +31,7 @@ type Config interface {
 	GetDomains() []string
 	GetGoogleProjectID() string
 	GetNaisNamespace() string
+	IsFeatureGateEnabled(gate string) bool
 	IsNetworkPolicyEnabled() bool
 }
 
@@ -150,6 +155,9 @@ func defaultEgressRules(cfg Config) []networkingv1.NetworkPolicyEgressRule {
 			},
 		},
 	}
+	if cfg.IsFeatureGateEnabled(GateDNSPorts) {
+		rules[0].Ports = dnsPorts()
+	}
 	if cfg.GetAivenRange() != "" {
 		rules = append(rules, networkingv1.NetworkPolicyEgressRule{
 			To: []networkingv1.NetworkPolicyPeer{
@@ -164,6 +172,18 @@ func defaultEgressRules(cfg Config) []networkingv1.NetworkPolicyEgressRule {
 	return rules
 }
 
+func dnsPorts() []networkingv1.NetworkPolicyPort {
+	port := intstr.FromInt32(53)
+	ports := make([]networkingv1.NetworkPolicyPort, 0, 2)
+	for _, protocol := range []corev1.Protocol{corev1.ProtocolUDP, corev1.ProtocolTCP} {
+		ports = append(ports, networkingv1.NetworkPolicyPort{
+			Protocol: &protocol,
+			Port:     &port,
+		})
+	}
+	return ports
+}
+
 func ingressRules(ingress []nais_io_v1.Ingress, policy *nais_io_v1.AccessPolicy, cfg Config) []networkingv1.NetworkPolicyIngressRule {
 	rules := make([]networkingv1.NetworkPolicyIngressRule, 0)
 
@@ -177,6 +177,9 @@ func logLabels(obs *nais_io_v1.Observability, cfg config.Logging) (map[string]st
 
 			labels[logLabelPrefix+destination.ID] = "true"
 		}
+	} else if len(cfg.DefaultDestination) > 0 {
+		labels[logLabelDefault] = "false"
+		labels[logLabelPrefix+cfg.DefaultDestination] = "true"
 	}
 
 	return labels, nil
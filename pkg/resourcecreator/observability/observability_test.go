@@ -288,6 +288,23 @@ func TestLogLabels(t *testing.T) {
 			},
 			expectedError: nil,
 		},
+		{
+			name: "Configured default destination",
+			obs: &nais_io_v1.Observability{
+				Logging: &nais_io_v1.Logging{
+					Enabled: true,
+				},
+			},
+			cfg: config.Logging{
+				DefaultDestination: "destination2",
+				Destinations:       []string{"destination1", "destination2"},
+			},
+			expectedLabels: map[string]string{
+				"logs.nais.io/flow-default":      "false",
+				"logs.nais.io/flow-destination2": "true",
+			},
+			expectedError: nil,
+		},
 		{
 			name: "Disabled",
 			obs: &nais_io_v1.Observability{
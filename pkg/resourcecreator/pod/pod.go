@@ -14,6 +14,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
+	naisannotations "github.com/nais/naiserator/pkg/annotations"
 	"github.com/nais/naiserator/pkg/naiserator/config"
 	"github.com/nais/naiserator/pkg/resourcecreator/resource"
 )
@@ -155,7 +156,7 @@ func configureSecurityContext(annotations map[string]string) *corev1.SecurityCon
 }
 
 func runAsUser(annotations map[string]string) int64 {
-	val, found := annotations["nais.io/run-as-user"]
+	val, found := annotations[naisannotations.RunAsUser]
 	if !found {
 		return 1069
 	}
@@ -170,7 +171,7 @@ func runAsUser(annotations map[string]string) int64 {
 }
 
 func runAsGroup(annotations map[string]string) int64 {
-	val, found := annotations["nais.io/run-as-group"]
+	val, found := annotations[naisannotations.RunAsGroup]
 	if !found {
 		return runAsUser(annotations)
 	}
@@ -540,7 +541,7 @@ func leadingSlash(s string) string {
 }
 
 func readOnlyFileSystem(annotations map[string]string) bool {
-	val, found := annotations["nais.io/read-only-file-system"]
+	val, found := annotations[naisannotations.ReadOnlyFileSystem]
 	if !found {
 		return true
 	}
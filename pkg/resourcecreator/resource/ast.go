@@ -1,6 +1,9 @@
 package resource
 
 import (
+	"maps"
+	"slices"
+
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -39,6 +42,22 @@ func NewAst() *Ast {
 	}
 }
 
+// Clone returns a copy of the AST that can be modified without affecting the original.
+// Only the slices and maps are copied; the objects they refer to are shared.
+func (a *Ast) Clone() *Ast {
+	return &Ast{
+		Operations:     slices.Clone(a.Operations),
+		Annotations:    maps.Clone(a.Annotations),
+		Containers:     slices.Clone(a.Containers),
+		Env:            slices.Clone(a.Env),
+		EnvFrom:        slices.Clone(a.EnvFrom),
+		InitContainers: slices.Clone(a.InitContainers),
+		Labels:         maps.Clone(a.Labels),
+		Volumes:        slices.Clone(a.Volumes),
+		VolumeMounts:   slices.Clone(a.VolumeMounts),
+	}
+}
+
 // Use AppendEnv for adding environment variables that depend on other environment variables, or to ensure that they can not be overridden by the user.
 func (a *Ast) AppendEnv(vars ...corev1.EnvVar) {
 	a.Env = append(a.Env, vars...)
@@ -4,6 +4,7 @@ import (
 	nais_io_v1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
 	nais_io_v1_alpha1 "github.com/nais/liberator/pkg/apis/nais.io/v1alpha1"
 
+	"github.com/nais/naiserator/pkg/progressive"
 	"github.com/nais/naiserator/pkg/resourcecreator/wonderwall"
 
 	corev1 "k8s.io/api/core/v1"
@@ -21,9 +22,96 @@ type Source interface {
 
 type Config interface {
 	wonderwall.Config
+	GetProgressiveState() *progressive.State
 }
 
+// Create creates the Service of the application.
+// During a progressive rollout, it only selects the pods of the track that gets all the ingress traffic,
+// so that traffic inside the cluster isn't sent to the canary before it is promoted.
 func Create(source Source, ast *resource.Ast, config Config) {
+	selector := map[string]string{"app": source.GetName()}
+	if track := rolloutTrack(config); len(track) > 0 {
+		selector[progressive.TrackLabel] = track
+	}
+	service := newService(source, config, resource.CreateObjectMeta(source), selector)
+	ast.AppendOperation(resource.OperationCreateOrUpdate, service)
+}
+
+// rolloutTrack returns the track selected by the application Service during a progressive rollout:
+// the stable pods until the canary gets all the traffic. It is empty when no rollout is in progress.
+func rolloutTrack(config Config) string {
+	state := config.GetProgressiveState()
+	switch {
+	case state == nil || state.Phase.Done():
+		return ""
+	case state.Weight == 100:
+		return progressive.TrackCanary
+	default:
+		return progressive.TrackStable
+	}
+}
+
+// CreateTrack creates a Service named <app>-<track> that only selects the pods labelled with the given track,
+// so that traffic can be split between the stable and canary Deployments of an application.
+func CreateTrack(source Source, ast *resource.Ast, config Config, trackLabel, track string) {
+	objectMeta := resource.CreateObjectMeta(source)
+	objectMeta.Name = TrackName(source, track)
+	service := newService(source, config, objectMeta, map[string]string{"app": source.GetName(), trackLabel: track})
+	ast.AppendOperation(resource.OperationCreateOrUpdate, service)
+}
+
+// TrackName is the name of the Service that selects the pods of a track.
+func TrackName(source resource.Source, track string) string {
+	return source.GetName() + "-" + track
+}
+
+// CreateSplit creates a Service named <app>-split that selects the pods of both tracks,
+// for ingress controllers that can only split traffic between the pods of a single Service.
+func CreateSplit(source Source, ast *resource.Ast, config Config) {
+	objectMeta := resource.CreateObjectMeta(source)
+	objectMeta.Name = SplitName(source)
+	service := newService(source, config, objectMeta, map[string]string{"app": source.GetName()})
+	ast.AppendOperation(resource.OperationCreateOrUpdate, service)
+}
+
+// SplitName is the name of the Service that selects the pods of both tracks.
+func SplitName(source resource.Source) string {
+	return source.GetName() + "-split"
+}
+
+// CreateMaintenance creates a Service named <app>-maintenance that forwards to the shared maintenance backend,
+// so that the ingresses of an application in maintenance mode can send traffic to it.
+func CreateMaintenance(source resource.Source, ast *resource.Ast, backend string) {
+	objectMeta := resource.CreateObjectMeta(source)
+	objectMeta.Name = MaintenanceName(source)
+
+	service := &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Service",
+			APIVersion: "v1",
+		},
+		ObjectMeta: objectMeta,
+		Spec: corev1.ServiceSpec{
+			Type:         corev1.ServiceTypeExternalName,
+			ExternalName: backend,
+			Ports: []corev1.ServicePort{
+				{
+					Name:     "http",
+					Protocol: corev1.ProtocolTCP,
+					Port:     int32(nais_io_v1_alpha1.DefaultServicePort),
+				},
+			},
+		},
+	}
+	ast.AppendOperation(resource.OperationCreateOrUpdate, service)
+}
+
+// MaintenanceName is the name of the Service that forwards to the shared maintenance backend.
+func MaintenanceName(source resource.Source) string {
+	return source.GetName() + "-maintenance"
+}
+
+func newService(source Source, config Config, objectMeta metav1.ObjectMeta, selector map[string]string) *corev1.Service {
 	svc := source.GetService()
 
 	targetPort := intstr.FromString(nais_io_v1_alpha1.DefaultPortName)
@@ -32,15 +120,15 @@ func Create(source Source, ast *resource.Ast, config Config) {
 		targetPort = intstr.FromInt32(wonderwall.Port)
 	}
 
-	service := &corev1.Service{
+	return &corev1.Service{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Service",
 			APIVersion: "v1",
 		},
-		ObjectMeta: resource.CreateObjectMeta(source),
+		ObjectMeta: objectMeta,
 		Spec: corev1.ServiceSpec{
 			Type:     corev1.ServiceTypeClusterIP,
-			Selector: map[string]string{"app": source.GetName()},
+			Selector: selector,
 			Ports: []corev1.ServicePort{
 				{
 					Name:       svc.Protocol,
@@ -51,6 +139,4 @@ func Create(source Source, ast *resource.Ast, config Config) {
 			},
 		},
 	}
-
-	ast.AppendOperation(resource.OperationCreateOrUpdate, service)
 }
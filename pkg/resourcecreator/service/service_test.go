@@ -8,6 +8,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	core "k8s.io/api/core/v1"
 
+	"github.com/nais/naiserator/pkg/progressive"
 	"github.com/nais/naiserator/pkg/resourcecreator/resource"
 	"github.com/nais/naiserator/pkg/resourcecreator/service"
 	"github.com/nais/naiserator/pkg/test/fixtures"
@@ -43,4 +44,18 @@ func TestGetService(t *testing.T) {
 		assert.Equal(t, "redis", port.Name)
 		assert.Equal(t, 1337, int(port.Port))
 	})
+
+	t.Run("selects a single track during a progressive rollout", func(t *testing.T) {
+		app := fixtures.MinimalApplication()
+		err := app.ApplyDefaults()
+		assert.NoError(t, err)
+
+		for weight, track := range map[int]string{25: progressive.TrackStable, 100: progressive.TrackCanary} {
+			ast := resource.NewAst()
+			opts := &generators.Options{Progressive: &progressive.State{Phase: progressive.PhaseProgressing, Weight: weight}}
+			service.Create(app, ast, opts)
+			svc := ast.Operations[0].Resource.(*core.Service)
+			assert.Equal(t, map[string]string{"app": app.GetName(), progressive.TrackLabel: track}, svc.Spec.Selector)
+		}
+	})
 }
@@ -0,0 +1,166 @@
+package statefulset
+
+import (
+	"fmt"
+
+	nais_io_v1alpha1 "github.com/nais/liberator/pkg/apis/nais.io/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/nais/naiserator/pkg/annotations"
+	"github.com/nais/naiserator/pkg/resourcecreator/deployment"
+	"github.com/nais/naiserator/pkg/resourcecreator/pod"
+	"github.com/nais/naiserator/pkg/resourcecreator/resource"
+)
+
+type Source = deployment.Source
+
+type Config = deployment.Config
+
+// Enabled returns true if the Application runs as a StatefulSet instead of a Deployment.
+func Enabled(source resource.Source) bool {
+	return source.GetAnnotations()[annotations.WorkloadMode] == annotations.WorkloadModeStatefulSet
+}
+
+// HeadlessServiceName is the name of the service that gives each pod of the StatefulSet a stable DNS name,
+// on the form <name>-<ordinal>.<name>-headless.<namespace>.svc.
+func HeadlessServiceName(source resource.Source) string {
+	return source.GetName() + "-headless"
+}
+
+// Create generates a StatefulSet with a persistent volume for each replica and volume claim, and the headless service
+// that governs it. The app container must already have been added to the AST.
+func Create(app Source, ast *resource.Ast, cfg Config) error {
+	claims, err := annotations.ParseVolumeClaims(app.GetAnnotations()[annotations.VolumeClaims])
+	if err != nil {
+		return fmt.Errorf("create statefulset: %w", err)
+	}
+
+	err = mountVolumeClaims(app, ast, claims)
+	if err != nil {
+		return fmt.Errorf("create statefulset: %w", err)
+	}
+
+	podSpec, err := pod.CreateSpec(ast, cfg, app.GetName(), app.GetAnnotations(), corev1.RestartPolicyAlways, app.GetTerminationGracePeriodSeconds())
+	if err != nil {
+		return fmt.Errorf("create statefulset: %w", err)
+	}
+
+	objectMeta := resource.CreateObjectMeta(app)
+	if val, ok := app.GetAnnotations()["kubernetes.io/change-cause"]; ok {
+		objectMeta.Annotations["kubernetes.io/change-cause"] = val
+	}
+	objectMeta.Annotations["reloader.stakater.com/search"] = "true"
+
+	statefulSet := &appsv1.StatefulSet{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "StatefulSet",
+			APIVersion: "apps/v1",
+		},
+		ObjectMeta: objectMeta,
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: new(cfg.GetNumReplicas()),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": app.GetName()},
+			},
+			ServiceName:         HeadlessServiceName(app),
+			PodManagementPolicy: podManagementPolicy(app),
+			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+				Type: appsv1.RollingUpdateStatefulSetStrategyType,
+			},
+			RevisionHistoryLimit: new(int32(3)),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: pod.CreateAppObjectMeta(app, ast, cfg),
+				Spec:       *podSpec,
+			},
+			VolumeClaimTemplates: volumeClaimTemplates(claims),
+		},
+	}
+
+	ast.AppendOperation(resource.OperationCreateOrUpdate, headlessService(app))
+	ast.AppendOperation(resource.OperationCreateOrUpdate, statefulSet)
+
+	return nil
+}
+
+func podManagementPolicy(app Source) appsv1.PodManagementPolicyType {
+	if app.GetAnnotations()[annotations.PodManagementPolicy] == string(appsv1.ParallelPodManagement) {
+		return appsv1.ParallelPodManagement
+	}
+	return appsv1.OrderedReadyPodManagement
+}
+
+// mountVolumeClaims mounts the persistent volumes in the app container.
+func mountVolumeClaims(app Source, ast *resource.Ast, claims []annotations.VolumeClaim) error {
+	for i := range ast.Containers {
+		if ast.Containers[i].Name != app.GetName() {
+			continue
+		}
+		for _, claim := range claims {
+			ast.Containers[i].VolumeMounts = append(ast.Containers[i].VolumeMounts, corev1.VolumeMount{
+				Name:      claim.Name,
+				MountPath: claim.MountPath,
+			})
+		}
+		return nil
+	}
+	return fmt.Errorf("BUG: app container must be created before the statefulset")
+}
+
+func volumeClaimTemplates(claims []annotations.VolumeClaim) []corev1.PersistentVolumeClaim {
+	templates := make([]corev1.PersistentVolumeClaim, 0, len(claims))
+	for _, claim := range claims {
+		template := corev1.PersistentVolumeClaim{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "PersistentVolumeClaim",
+				APIVersion: "v1",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name: claim.Name,
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceStorage: claim.Size,
+					},
+				},
+			},
+		}
+		if len(claim.StorageClass) > 0 {
+			template.Spec.StorageClassName = new(claim.StorageClass)
+		}
+		templates = append(templates, template)
+	}
+	return templates
+}
+
+// headlessService lists all pods, including those that are not ready, so that replicas can find each other on startup.
+func headlessService(app Source) *corev1.Service {
+	objectMeta := resource.CreateObjectMeta(app)
+	objectMeta.Name = HeadlessServiceName(app)
+
+	return &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Service",
+			APIVersion: "v1",
+		},
+		ObjectMeta: objectMeta,
+		Spec: corev1.ServiceSpec{
+			Type:                     corev1.ServiceTypeClusterIP,
+			ClusterIP:                corev1.ClusterIPNone,
+			Selector:                 map[string]string{"app": app.GetName()},
+			PublishNotReadyAddresses: true,
+			Ports: []corev1.ServicePort{
+				{
+					Name:       nais_io_v1alpha1.DefaultPortName,
+					Protocol:   corev1.ProtocolTCP,
+					Port:       int32(app.GetPort()),
+					TargetPort: intstr.FromString(nais_io_v1alpha1.DefaultPortName),
+				},
+			},
+		},
+	}
+}
@@ -21,7 +21,7 @@ import (
 )
 
 // Maintain a list of resources that should be cleaned up during Application synchronization.
-// Orphaned types with matching label 'app=NAME' but without owner references are automatically removed.
+// Resources of these types that are owned by the application, but no longer generated, are automatically removed.
 // It is too expensive to list all known Kubernetes types, so we need to have some knowledge of which types to care about.
 // These are usually the types we persist to the cluster with names different than the application name.
 
@@ -30,6 +30,7 @@ func GenericListers() []client.ObjectList {
 	return []client.ObjectList{
 		// Kubernetes internals
 		&appsv1.DeploymentList{},
+		&appsv1.StatefulSetList{},
 		&v1.CronJobList{},
 		&v1.JobList{},
 		&autoscalev2.HorizontalPodAutoscalerList{},
@@ -0,0 +1,91 @@
+package synchronizer
+
+import (
+	"github.com/nais/liberator/pkg/events"
+	"github.com/nais/naiserator/pkg/resourcecreator/resource"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Condition types following the kstatus conventions, so that Flux, Argo CD and `kubectl wait`
+// can tell when a workload has been rolled out, is in progress, or needs attention.
+// See https://github.com/kubernetes-sigs/cli-utils/blob/master/pkg/kstatus/README.md
+const (
+	ConditionReady       = "Ready"
+	ConditionReconciling = "Reconciling"
+	ConditionStalled     = "Stalled"
+
+	// ConditionDegraded is true when optional integrations were skipped, see SoftFailAnnotation.
+	ConditionDegraded = "Degraded"
+
+	// ConditionSidecarImages names the sidecar channel and images used for the workload.
+	ConditionSidecarImages = "SidecarImages"
+
+	// ConditionProgressiveDelivery is true while a canary or blue-green rollout is in progress.
+	// The reason is the phase of the rollout.
+	ConditionProgressiveDelivery = "ProgressiveDelivery"
+
+	// ConditionPreview is true for previews, and tells when they expire.
+	ConditionPreview = "Preview"
+)
+
+type kstatus struct {
+	ready       metav1.ConditionStatus
+	reconciling metav1.ConditionStatus
+	stalled     metav1.ConditionStatus
+}
+
+var (
+	kstatusReady       = kstatus{ready: metav1.ConditionTrue, reconciling: metav1.ConditionFalse, stalled: metav1.ConditionFalse}
+	kstatusReconciling = kstatus{ready: metav1.ConditionFalse, reconciling: metav1.ConditionTrue, stalled: metav1.ConditionFalse}
+	kstatusStalled     = kstatus{ready: metav1.ConditionFalse, reconciling: metav1.ConditionFalse, stalled: metav1.ConditionTrue}
+)
+
+// synchronizationStateConditions maps every synchronization state to conditions.
+// States that resolve by themselves are Reconciling; states where the rollout can't progress without a change from the
+// user, or until a freeze window ends, are Stalled, even if they are retried.
+var synchronizationStateConditions = map[string]kstatus{
+	events.Synchronized:          kstatusReconciling,
+	events.Retrying:              kstatusReconciling,
+	events.RolloutComplete:       kstatusReady,
+	events.FailedPrepare:         kstatusStalled,
+	events.FailedGenerate:        kstatusStalled,
+	events.FailedSynchronization: kstatusStalled,
+	DestructiveChangesBlocked:    kstatusStalled,
+	DeletionBlocked:              kstatusStalled,
+	Frozen:                       kstatusStalled,
+	Recreating:                   kstatusReconciling,
+	RolledBack:                   kstatusStalled,
+	RolloutFailed:                kstatusStalled,
+}
+
+// setSynchronizationState sets the synchronization state along with the matching kstatus conditions.
+// Other conditions, such as the per-state conditions set by liberator, are left alone.
+// Unknown states leave the workload as Reconciling, with Ready unknown.
+func setSynchronizationState(app resource.Source, state, message string) {
+	status := app.GetStatus()
+	status.SetSynchronizationStateWithCondition(state, message)
+
+	conditions, ok := synchronizationStateConditions[state]
+	if !ok {
+		conditions = kstatus{ready: metav1.ConditionUnknown, reconciling: metav1.ConditionTrue, stalled: metav1.ConditionFalse}
+	}
+
+	if status.Conditions == nil {
+		status.Conditions = &[]metav1.Condition{}
+	}
+
+	for conditionType, conditionStatus := range map[string]metav1.ConditionStatus{
+		ConditionReady:       conditions.ready,
+		ConditionReconciling: conditions.reconciling,
+		ConditionStalled:     conditions.stalled,
+	} {
+		meta.SetStatusCondition(status.Conditions, metav1.Condition{
+			Type:               conditionType,
+			Status:             conditionStatus,
+			ObservedGeneration: app.GetGeneration(),
+			Reason:             state,
+			Message:            message,
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package synchronizer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nais/liberator/pkg/events"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/nais/naiserator/pkg/progressive"
+	"github.com/nais/naiserator/pkg/test/fixtures"
+)
+
+func TestSetSynchronizationState(t *testing.T) {
+	app := fixtures.MinimalApplication()
+	app.SetGeneration(3)
+	app.GetStatus().Conditions = &[]metav1.Condition{{Type: "RolloutComplete", Status: metav1.ConditionTrue}}
+
+	assertConditions := func(ready, reconciling, stalled metav1.ConditionStatus, reason string) {
+		t.Helper()
+		for conditionType, expected := range map[string]metav1.ConditionStatus{
+			ConditionReady:       ready,
+			ConditionReconciling: reconciling,
+			ConditionStalled:     stalled,
+		} {
+			condition := meta.FindStatusCondition(*app.GetStatus().Conditions, conditionType)
+			if assert.NotNil(t, condition, conditionType) {
+				assert.Equal(t, expected, condition.Status, conditionType)
+				assert.Equal(t, reason, condition.Reason)
+				assert.Equal(t, int64(3), condition.ObservedGeneration)
+			}
+		}
+	}
+
+	setSynchronizationState(app, events.Synchronized, "waiting for completion")
+	assert.Equal(t, events.Synchronized, app.GetStatus().SynchronizationState)
+	assertConditions(metav1.ConditionFalse, metav1.ConditionTrue, metav1.ConditionFalse, events.Synchronized)
+
+	setSynchronizationState(app, events.RolloutComplete, "Successfully deployed.")
+	assertConditions(metav1.ConditionTrue, metav1.ConditionFalse, metav1.ConditionFalse, events.RolloutComplete)
+
+	setSynchronizationState(app, events.FailedGenerate, "invalid spec")
+	assertConditions(metav1.ConditionFalse, metav1.ConditionFalse, metav1.ConditionTrue, events.FailedGenerate)
+
+	setSynchronizationState(app, events.FailedPrepare, "policy violation: image must be signed")
+	assertConditions(metav1.ConditionFalse, metav1.ConditionFalse, metav1.ConditionTrue, events.FailedPrepare)
+
+	setSynchronizationState(app, Frozen, "deploys are frozen until 08:00")
+	assertConditions(metav1.ConditionFalse, metav1.ConditionFalse, metav1.ConditionTrue, Frozen)
+
+	setSynchronizationState(app, RolloutFailed, "ReplicaSet has timed out progressing.")
+	assertConditions(metav1.ConditionFalse, metav1.ConditionFalse, metav1.ConditionTrue, RolloutFailed)
+
+	setSynchronizationState(app, Recreating, "waiting for Job foo to be deleted before recreating it")
+	assertConditions(metav1.ConditionFalse, metav1.ConditionTrue, metav1.ConditionFalse, Recreating)
+
+	setSynchronizationState(app, "SomethingNew", "unmapped state")
+	assertConditions(metav1.ConditionUnknown, metav1.ConditionTrue, metav1.ConditionFalse, "SomethingNew")
+
+	assert.NotNil(t, meta.FindStatusCondition(*app.GetStatus().Conditions, "RolloutComplete"), "conditions set by others are kept")
+}
+
+func TestSetSidecarImages(t *testing.T) {
+	app := fixtures.MinimalApplication()
+
+	setSidecarImages(app, "canary", map[string]string{"wonderwall": "wonderwall:2", "elector": "elector:1"})
+
+	condition := meta.FindStatusCondition(*app.GetStatus().Conditions, ConditionSidecarImages)
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, metav1.ConditionTrue, condition.Status)
+		assert.Equal(t, "Sidecar channel canary: elector=elector:1, wonderwall=wonderwall:2", condition.Message)
+	}
+}
+
+func TestSetProgressiveDelivery(t *testing.T) {
+	app := fixtures.MinimalApplication()
+
+	setProgressiveDelivery(app, progressive.State{Phase: progressive.PhaseProgressing, Message: "Step 1 of 2"})
+	condition := meta.FindStatusCondition(*app.GetStatus().Conditions, ConditionProgressiveDelivery)
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, metav1.ConditionTrue, condition.Status)
+		assert.Equal(t, string(progressive.PhaseProgressing), condition.Reason)
+	}
+
+	setProgressiveDelivery(app, progressive.State{Phase: progressive.PhaseRolledBack, Message: "Canary analysis failed"})
+	condition = meta.FindStatusCondition(*app.GetStatus().Conditions, ConditionProgressiveDelivery)
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, metav1.ConditionFalse, condition.Status)
+		assert.Equal(t, string(progressive.PhaseRolledBack), condition.Reason)
+		assert.Equal(t, "Canary analysis failed", condition.Message)
+	}
+
+	setSynchronizationState(app, RolledBack, "Canary analysis failed")
+	assert.NotNil(t, meta.FindStatusCondition(*app.GetStatus().Conditions, ConditionProgressiveDelivery), "kept between reconciles")
+	assert.True(t, meta.IsStatusConditionTrue(*app.GetStatus().Conditions, ConditionStalled))
+}
+
+func TestSetPreview(t *testing.T) {
+	app := fixtures.MinimalApplication()
+
+	setPreview(app, time.Date(2026, 10, 22, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60)))
+	condition := meta.FindStatusCondition(*app.GetStatus().Conditions, ConditionPreview)
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, metav1.ConditionTrue, condition.Status)
+		assert.Equal(t, PreviewActive, condition.Reason)
+		assert.Equal(t, "Preview expires at 2026-10-22T10:00:00Z", condition.Message)
+	}
+
+	setSynchronizationState(app, events.Synchronized, "Deployment has been processed")
+	assert.NotNil(t, meta.FindStatusCondition(*app.GetStatus().Conditions, ConditionPreview), "kept between reconciles")
+}
@@ -0,0 +1,96 @@
+package synchronizer
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/nais/naiserator/pkg/annotations"
+	"github.com/nais/naiserator/pkg/naiserator/config"
+	"github.com/nais/naiserator/pkg/resourcecreator/resource"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// SoftFailAnnotation lists the optional integrations that may fail without failing the rollout of the workload,
+	// comma-separated, or "*" for all of them. Integrations can also be set to soft-fail for the whole cluster.
+	SoftFailAnnotation = annotations.SoftFail
+
+	Degraded            = "Degraded"
+	IntegrationFailed   = "IntegrationFailed"
+	IntegrationsHealthy = "IntegrationsHealthy"
+)
+
+// Degradation is a failing optional integration that was skipped instead of failing the rollout.
+type Degradation struct {
+	Integration string
+	Message     string
+}
+
+// Degradable is implemented by generator options that record optional integrations skipped during Prepare and Generate.
+type Degradable interface {
+	Degradations() []Degradation
+}
+
+// integrationGroups maps the API groups of generated resources to the optional integration that creates them.
+var integrationGroups = map[string]string{
+	"aiven.io":              config.IntegrationAiven,
+	"aiven.nais.io":         config.IntegrationAiven,
+	"kafka.nais.io":         config.IntegrationAiven,
+	"data.nais.io":          config.IntegrationPostgres,
+	"networking.gke.io":     config.IntegrationFQDNPolicy,
+	"monitoring.coreos.com": config.IntegrationPrometheus,
+}
+
+// softFailIntegration returns the optional integration that creates resources of this kind,
+// if soft-fail is enabled for it. Otherwise, it returns an empty string.
+func softFailIntegration(cfg config.Config, source resource.Source, gvk schema.GroupVersionKind) string {
+	integration, found := integrationGroups[gvk.Group]
+	if !found || !cfg.SoftFail(integration, source.GetAnnotations()[SoftFailAnnotation]) {
+		return ""
+	}
+	return integration
+}
+
+// degradedIntegration returns true if resources of this kind belong to an optional integration that was skipped
+// in this rollout. Their live objects must be kept as they are, so that a failing integration doesn't take down what
+// is already running.
+func degradedIntegration(degradations []Degradation, gvk schema.GroupVersionKind) bool {
+	integration, found := integrationGroups[gvk.Group]
+	return found && slices.ContainsFunc(degradations, func(degradation Degradation) bool {
+		return degradation.Integration == integration
+	})
+}
+
+func degradationMessage(degradations []Degradation) string {
+	messages := make([]string, 0, len(degradations))
+	for _, degradation := range degradations {
+		messages = append(messages, fmt.Sprintf("%s: %s", degradation.Integration, degradation.Message))
+	}
+	return "Optional integrations failed; " + strings.Join(messages, "; ")
+}
+
+// setDegraded sets the Degraded condition, naming every failing integration.
+func setDegraded(app resource.Source, degradations []Degradation) {
+	status := app.GetStatus()
+	if status.Conditions == nil {
+		status.Conditions = &[]metav1.Condition{}
+	}
+
+	condition := metav1.Condition{
+		Type:               ConditionDegraded,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: app.GetGeneration(),
+		Reason:             IntegrationsHealthy,
+		Message:            "All integrations were set up",
+	}
+	if len(degradations) > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = IntegrationFailed
+		condition.Message = degradationMessage(degradations)
+	}
+
+	meta.SetStatusCondition(status.Conditions, condition)
+}
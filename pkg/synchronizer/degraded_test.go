@@ -0,0 +1,54 @@
+package synchronizer
+
+import (
+	"context"
+	"testing"
+
+	aiven_nais_io_v1 "github.com/nais/liberator/pkg/apis/aiven.nais.io/v1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/nais/naiserator/pkg/naiserator/config"
+	"github.com/nais/naiserator/pkg/test/fixtures"
+)
+
+func TestUnreferencedKeepsDegradedIntegrations(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, corev1.AddToScheme(scheme))
+	assert.NoError(t, aiven_nais_io_v1.AddToScheme(scheme))
+
+	app := fixtures.MinimalApplication()
+	app.SetUID("app-uid")
+	objectMeta := metav1.ObjectMeta{
+		Name:            app.GetName(),
+		Namespace:       app.GetNamespace(),
+		Labels:          map[string]string{"app": app.GetName()},
+		OwnerReferences: []metav1.OwnerReference{app.GetOwnerReference()},
+	}
+	aivenApp := &aiven_nais_io_v1.AivenApplication{ObjectMeta: *objectMeta.DeepCopy()}
+	configMap := &corev1.ConfigMap{ObjectMeta: *objectMeta.DeepCopy()}
+
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(aivenApp, configMap).Build()
+	listers := []client.ObjectList{&aiven_nais_io_v1.AivenApplicationList{}, &corev1.ConfigMapList{}}
+	synchronizer := NewSynchronizer(cli, cli, config.Config{}, nil, listers, scheme)
+
+	t.Run("live resources of a degraded integration are kept", func(t *testing.T) {
+		unreferenced, err := synchronizer.Unreferenced(context.Background(), Rollout{
+			Source:       app,
+			Degradations: []Degradation{{Integration: config.IntegrationAiven, Message: "no such topic"}},
+		})
+		assert.NoError(t, err)
+		assert.Len(t, unreferenced, 1)
+		assert.IsType(t, &corev1.ConfigMap{}, unreferenced[0])
+	})
+
+	t.Run("resources of healthy integrations are cleaned up", func(t *testing.T) {
+		unreferenced, err := synchronizer.Unreferenced(context.Background(), Rollout{Source: app})
+		assert.NoError(t, err)
+		assert.Len(t, unreferenced, 2)
+	})
+}
@@ -0,0 +1,118 @@
+package synchronizer
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	google_nais_io_v1 "github.com/nais/liberator/pkg/apis/google.nais.io/v1"
+	sql_cnrm_cloud_google_com_v1beta1 "github.com/nais/liberator/pkg/apis/sql.cnrm.cloud.google.com/v1beta1"
+	storage_cnrm_cloud_google_com_v1beta1 "github.com/nais/liberator/pkg/apis/storage.cnrm.cloud.google.com/v1beta1"
+	log "github.com/sirupsen/logrus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nais/naiserator/pkg/annotations"
+	"github.com/nais/naiserator/pkg/resourcecreator/google"
+	"github.com/nais/naiserator/pkg/resourcecreator/resource"
+)
+
+const (
+	DeletionProtectionAnnotation = annotations.DeletionProtection
+	ConfirmDeletionAnnotation    = annotations.ConfirmDeletion
+	DeletionBlocked              = "DeletionBlocked"
+)
+
+// deletionProtected returns true if deletion of the source must be confirmed before its stateful resources are removed.
+// The workload annotation takes precedence over the cluster default.
+func (n *Synchronizer) deletionProtected(source resource.Source) bool {
+	value, found := source.GetAnnotations()[DeletionProtectionAnnotation]
+	if !found {
+		return n.config.Features.DeletionProtection
+	}
+
+	protected, err := strconv.ParseBool(value)
+	if err != nil {
+		log.WithFields(source.LogFields()).Warnf("Invalid value %q for annotation %s; using cluster default", value, DeletionProtectionAnnotation)
+		return n.config.Features.DeletionProtection
+	}
+
+	return protected
+}
+
+func deletionConfirmed(source resource.Source) bool {
+	confirmed, _ := strconv.ParseBool(source.GetAnnotations()[ConfirmDeletionAnnotation])
+	return confirmed
+}
+
+// heldForDeletion returns the stateful resources that would be permanently deleted along with the source,
+// if deletion is protected and has not been confirmed.
+func (n *Synchronizer) heldForDeletion(ctx context.Context, source resource.Source) ([]string, error) {
+	if !n.deletionProtected(source) || deletionConfirmed(source) {
+		return nil, nil
+	}
+
+	lost, err := n.cascadingStatefulResources(ctx, source)
+	if err != nil {
+		return nil, fmt.Errorf("discover stateful resources: %w", err)
+	}
+
+	return lost, nil
+}
+
+func deletionHeldMessage(lost []string) string {
+	return fmt.Sprintf(
+		"Deletion is held because it will permanently delete %s; set the annotation %s=true to proceed",
+		strings.Join(lost, ", "), ConfirmDeletionAnnotation,
+	)
+}
+
+// cascadingStatefulResources lists resources owned by the source that take their data with them when deleted.
+// Only the Google resources are checked, and only if Config Connector is enabled.
+func (n *Synchronizer) cascadingStatefulResources(ctx context.Context, source resource.Source) ([]string, error) {
+	lost := make([]string, 0)
+
+	if !n.config.Features.CNRM {
+		return lost, nil
+	}
+
+	listOpts := []client.ListOption{
+		client.InNamespace(source.GetNamespace()),
+		client.MatchingLabels{"app": source.GetName()},
+	}
+
+	instances := &sql_cnrm_cloud_google_com_v1beta1.SQLInstanceList{}
+	err := n.List(ctx, instances, listOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("list SQL instances: %w", err)
+	}
+	for _, instance := range instances.Items {
+		if isOwnedBy(source, instance.GetOwnerReferences()) && instance.GetAnnotations()[google.DeletionPolicyAnnotation] != google.DeletionPolicyAbandon {
+			lost = append(lost, fmt.Sprintf("SQL instance %q", instance.GetName()))
+		}
+	}
+
+	buckets := &storage_cnrm_cloud_google_com_v1beta1.StorageBucketList{}
+	err = n.List(ctx, buckets, listOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("list buckets: %w", err)
+	}
+	for _, bucket := range buckets.Items {
+		if isOwnedBy(source, bucket.GetOwnerReferences()) && bucket.GetAnnotations()[google.DeletionPolicyAnnotation] != google.DeletionPolicyAbandon {
+			lost = append(lost, fmt.Sprintf("bucket %q", bucket.GetName()))
+		}
+	}
+
+	datasets := &google_nais_io_v1.BigQueryDatasetList{}
+	err = n.List(ctx, datasets, listOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("list BigQuery datasets: %w", err)
+	}
+	for _, dataset := range datasets.Items {
+		if isOwnedBy(source, dataset.GetOwnerReferences()) && dataset.Spec.CascadingDelete {
+			lost = append(lost, fmt.Sprintf("BigQuery dataset %q", dataset.GetName()))
+		}
+	}
+
+	return lost, nil
+}
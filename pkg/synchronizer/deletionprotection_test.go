@@ -0,0 +1,63 @@
+package synchronizer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	storage_cnrm_cloud_google_com_v1beta1 "github.com/nais/liberator/pkg/apis/storage.cnrm.cloud.google.com/v1beta1"
+	liberator_scheme "github.com/nais/liberator/pkg/scheme"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/nais/naiserator/pkg/naiserator/config"
+	"github.com/nais/naiserator/pkg/test/fixtures"
+)
+
+func TestHeldForDeletion(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_, err := liberator_scheme.AddAll(scheme)
+	assert.NoError(t, err)
+
+	app := fixtures.MinimalApplication()
+	app.SetUID("app-uid")
+	bucket := &storage_cnrm_cloud_google_com_v1beta1.StorageBucket{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "my-bucket",
+			Namespace:       app.GetNamespace(),
+			Labels:          map[string]string{"app": app.GetName()},
+			OwnerReferences: []metav1.OwnerReference{app.GetOwnerReference()},
+		},
+	}
+	cfg := config.Config{Features: config.Features{CNRM: true, DeletionProtection: true}}
+
+	t.Run("owned buckets hold the deletion", func(t *testing.T) {
+		cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(bucket).Build()
+		synchronizer := NewSynchronizer(cli, cli, cfg, nil, nil, scheme)
+
+		lost, err := synchronizer.heldForDeletion(context.Background(), app)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{`bucket "my-bucket"`}, lost)
+	})
+
+	t.Run("list errors are returned instead of holding the deletion", func(t *testing.T) {
+		listErr := errors.New("connection refused")
+		cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(bucket).WithInterceptorFuncs(interceptor.Funcs{
+			List: func(ctx context.Context, cli client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+				if _, ok := list.(*storage_cnrm_cloud_google_com_v1beta1.StorageBucketList); ok {
+					return listErr
+				}
+				return cli.List(ctx, list, opts...)
+			},
+		}).Build()
+		synchronizer := NewSynchronizer(cli, cli, cfg, nil, nil, scheme)
+
+		lost, err := synchronizer.heldForDeletion(context.Background(), app)
+		assert.ErrorIs(t, err, listErr)
+		assert.Empty(t, lost)
+	})
+}
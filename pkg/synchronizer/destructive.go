@@ -0,0 +1,244 @@
+package synchronizer
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	nais_io_v1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+	sql_cnrm_cloud_google_com_v1beta1 "github.com/nais/liberator/pkg/apis/sql.cnrm.cloud.google.com/v1beta1"
+	storage_cnrm_cloud_google_com_v1beta1 "github.com/nais/liberator/pkg/apis/storage.cnrm.cloud.google.com/v1beta1"
+	"github.com/nais/pgrator/pkg/api/datav1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nais/naiserator/pkg/annotations"
+	"github.com/nais/naiserator/pkg/resourcecreator/google"
+	"github.com/nais/naiserator/pkg/resourcecreator/resource"
+)
+
+const (
+	AcknowledgeDestructiveChangesAnnotation = annotations.AcknowledgeDestructiveChanges
+	DestructiveChangesBlocked               = "DestructiveChangesBlocked"
+)
+
+// ErrDestructiveChanges is returned from Prepare when the rollout would destroy or orphan stateful resources,
+// and the changes have not been acknowledged for the current correlation ID.
+type ErrDestructiveChanges struct {
+	Changes       []string
+	CorrelationID string
+}
+
+func (e *ErrDestructiveChanges) Error() string {
+	return fmt.Sprintf(
+		"rollout blocked because of destructive changes: %s; to proceed, set the annotation %s=%s",
+		strings.Join(e.Changes, "; "), AcknowledgeDestructiveChangesAnnotation, e.CorrelationID,
+	)
+}
+
+// statefulResources are the resources that hold data which is lost or orphaned when the resource goes away.
+type statefulResources struct {
+	SQLInstances []string
+	// Buckets maps bucket name to whether the bucket contents are deleted together with the bucket.
+	Buckets         map[string]bool
+	PostgresCluster string
+}
+
+// postgresClusterLabels select the connection pooler of a postgres cluster, for each postgres engine.
+var postgresClusterLabels = []string{"cluster-name", "cnpg.io/cluster"}
+
+type postgresSource interface {
+	GetPostgres() *nais_io_v1.Postgres
+}
+
+// classifyDestructiveChanges returns a description of every change from live to desired that destroys or orphans data.
+func classifyDestructiveChanges(desired, live statefulResources) []string {
+	changes := make([]string, 0)
+
+	for _, instance := range live.SQLInstances {
+		if slices.Contains(desired.SQLInstances, instance) {
+			continue
+		}
+		if len(desired.SQLInstances) > 0 {
+			changes = append(changes, fmt.Sprintf("SQL instance %q would be replaced by %q", instance, strings.Join(desired.SQLInstances, ", ")))
+		} else {
+			changes = append(changes, fmt.Sprintf("SQL instance %q would be removed", instance))
+		}
+	}
+
+	buckets := make([]string, 0, len(live.Buckets))
+	for bucket := range live.Buckets {
+		buckets = append(buckets, bucket)
+	}
+	slices.Sort(buckets)
+	for _, bucket := range buckets {
+		_, wanted := desired.Buckets[bucket]
+		if live.Buckets[bucket] && !wanted {
+			changes = append(changes, fmt.Sprintf("bucket %q with cascadingDelete would be deleted along with its contents", bucket))
+		}
+	}
+
+	if len(live.PostgresCluster) > 0 && live.PostgresCluster != desired.PostgresCluster {
+		if len(desired.PostgresCluster) > 0 {
+			changes = append(changes, fmt.Sprintf("postgres cluster would be switched from %q to %q", live.PostgresCluster, desired.PostgresCluster))
+		} else {
+			changes = append(changes, fmt.Sprintf("postgres cluster %q would be removed", live.PostgresCluster))
+		}
+	}
+
+	return changes
+}
+
+// checkDestructiveChanges compares the stateful resources wanted by the source with the ones live in the cluster.
+// Destructive changes block the rollout, unless they are acknowledged with the current correlation ID.
+// Every deployment gets a new correlation ID, so an acknowledgement only approves the deployment it was given for.
+func (n *Synchronizer) checkDestructiveChanges(ctx context.Context, source resource.Source, kube client.Client) error {
+	desired := desiredStatefulResources(source)
+
+	live, err := n.liveStatefulResources(ctx, source, kube)
+	if err != nil {
+		return fmt.Errorf("discover stateful resources: %w", err)
+	}
+
+	changes := classifyDestructiveChanges(desired, live)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	correlationID := source.CorrelationID()
+	if len(correlationID) > 0 && source.GetAnnotations()[AcknowledgeDestructiveChangesAnnotation] == correlationID {
+		return nil
+	}
+
+	return &ErrDestructiveChanges{
+		Changes:       changes,
+		CorrelationID: correlationID,
+	}
+}
+
+func desiredStatefulResources(source resource.Source) statefulResources {
+	desired := statefulResources{
+		Buckets: make(map[string]bool),
+	}
+
+	if gcp := source.GetGCP(); gcp != nil {
+		for _, instance := range gcp.SqlInstances {
+			name := instance.Name
+			if len(name) == 0 {
+				name = source.GetName()
+			}
+			desired.SQLInstances = append(desired.SQLInstances, name)
+		}
+		for _, bucket := range gcp.Buckets {
+			desired.Buckets[bucket.Name] = bucket.CascadingDelete
+		}
+	}
+
+	if src, ok := source.(postgresSource); ok && src.GetPostgres() != nil {
+		desired.PostgresCluster = src.GetPostgres().ClusterName
+	}
+
+	return desired
+}
+
+func (n *Synchronizer) liveStatefulResources(ctx context.Context, source resource.Source, kube client.Client) (statefulResources, error) {
+	live := statefulResources{
+		Buckets: make(map[string]bool),
+	}
+
+	listOpts := []client.ListOption{
+		client.InNamespace(source.GetNamespace()),
+		client.MatchingLabels{"app": source.GetName()},
+	}
+
+	if n.config.Features.GCP {
+		instances := &sql_cnrm_cloud_google_com_v1beta1.SQLInstanceList{}
+		err := kube.List(ctx, instances, listOpts...)
+		if err != nil {
+			return live, err
+		}
+		for _, instance := range instances.Items {
+			if isOwnedBy(source, instance.GetOwnerReferences()) {
+				live.SQLInstances = append(live.SQLInstances, instance.GetName())
+			}
+		}
+
+		buckets := &storage_cnrm_cloud_google_com_v1beta1.StorageBucketList{}
+		err = kube.List(ctx, buckets, listOpts...)
+		if err != nil {
+			return live, err
+		}
+		for _, bucket := range buckets.Items {
+			if isOwnedBy(source, bucket.GetOwnerReferences()) {
+				live.Buckets[bucket.GetName()] = bucket.GetAnnotations()[google.DeletionPolicyAnnotation] != google.DeletionPolicyAbandon
+			}
+		}
+	}
+
+	if n.config.Features.PostgresOperator {
+		cluster, err := livePostgresCluster(ctx, source, kube)
+		if err != nil {
+			return live, err
+		}
+		live.PostgresCluster = cluster
+	}
+
+	return live, nil
+}
+
+// livePostgresCluster finds the postgres cluster currently in use from the egress network policy that gives the
+// workload access to the cluster's connection pooler, and returns it only if the Postgres object still exists.
+func livePostgresCluster(ctx context.Context, source resource.Source, kube client.Client) (string, error) {
+	policies := &networkingv1.NetworkPolicyList{}
+	err := kube.List(ctx, policies, client.InNamespace(source.GetNamespace()), client.MatchingLabels{"app": source.GetName()})
+	if err != nil {
+		return "", err
+	}
+
+	cluster := ""
+	for _, policy := range policies.Items {
+		if !isOwnedBy(source, policy.GetOwnerReferences()) {
+			continue
+		}
+		for _, rule := range policy.Spec.Egress {
+			for _, peer := range rule.To {
+				if peer.PodSelector == nil {
+					continue
+				}
+				for _, label := range postgresClusterLabels {
+					if name, found := peer.PodSelector.MatchLabels[label]; found {
+						cluster = name
+					}
+				}
+			}
+		}
+	}
+	if len(cluster) == 0 {
+		return "", nil
+	}
+
+	key := client.ObjectKey{
+		Name:      cluster,
+		Namespace: source.GetNamespace(),
+	}
+	err = kube.Get(ctx, key, &datav1.Postgres{})
+	if errors.IsNotFound(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	return cluster, nil
+}
+
+func isOwnedBy(source resource.Source, ownerReferences []metav1.OwnerReference) bool {
+	for _, ref := range ownerReferences {
+		if ref.UID == source.GetUID() {
+			return true
+		}
+	}
+	return false
+}
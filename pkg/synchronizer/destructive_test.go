@@ -0,0 +1,138 @@
+package synchronizer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	nais_io_v1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+	nais_io_v1alpha1 "github.com/nais/liberator/pkg/apis/nais.io/v1alpha1"
+	"github.com/nais/pgrator/pkg/api/datav1"
+	"github.com/stretchr/testify/assert"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/nais/naiserator/pkg/annotations"
+	"github.com/nais/naiserator/pkg/naiserator/config"
+	"github.com/nais/naiserator/pkg/resourcecreator/resource"
+	"github.com/nais/naiserator/pkg/test/fixtures"
+)
+
+func TestClassifyDestructiveChanges(t *testing.T) {
+	live := statefulResources{
+		SQLInstances: []string{"myapplication"},
+		Buckets: map[string]bool{
+			"cascading": true,
+			"abandoned": false,
+		},
+		PostgresCluster: "mycluster",
+	}
+
+	testCases := []struct {
+		name     string
+		desired  statefulResources
+		expected []string
+	}{
+		{
+			name: "unchanged",
+			desired: statefulResources{
+				SQLInstances:    []string{"myapplication"},
+				Buckets:         map[string]bool{"cascading": true, "abandoned": false},
+				PostgresCluster: "mycluster",
+			},
+			expected: []string{},
+		},
+		{
+			name: "new resources are not destructive",
+			desired: statefulResources{
+				SQLInstances:    []string{"myapplication"},
+				Buckets:         map[string]bool{"cascading": true, "abandoned": false, "new": true},
+				PostgresCluster: "mycluster",
+			},
+			expected: []string{},
+		},
+		{
+			name: "renamed sql instance and switched postgres cluster",
+			desired: statefulResources{
+				SQLInstances:    []string{"renamed"},
+				Buckets:         map[string]bool{"cascading": true},
+				PostgresCluster: "othercluster",
+			},
+			expected: []string{
+				`SQL instance "myapplication" would be replaced by "renamed"`,
+				`postgres cluster would be switched from "mycluster" to "othercluster"`,
+			},
+		},
+		{
+			name:    "everything removed",
+			desired: statefulResources{},
+			expected: []string{
+				`SQL instance "myapplication" would be removed`,
+				`bucket "cascading" with cascadingDelete would be deleted along with its contents`,
+				`postgres cluster "mycluster" would be removed`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, classifyDestructiveChanges(tc.desired, live))
+		})
+	}
+}
+
+func TestCheckDestructiveChanges(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, datav1.AddToScheme(scheme))
+	assert.NoError(t, networkingv1.AddToScheme(scheme))
+
+	app := fixtures.MinimalApplication()
+	app.SetUID("123456")
+	app.SetAnnotations(map[string]string{annotations.DeploymentCorrelationID: "deploy-1"})
+	app.Spec.Postgres = &nais_io_v1.Postgres{ClusterName: "othercluster"}
+
+	// Access to the cluster in use is given by the live egress network policy.
+	objectMeta := resource.CreateObjectMeta(app)
+	objectMeta.Name = "pg-" + app.GetName()
+	egress := &networkingv1.NetworkPolicy{
+		ObjectMeta: objectMeta,
+		Spec: networkingv1.NetworkPolicySpec{
+			Egress: []networkingv1.NetworkPolicyEgressRule{{
+				To: []networkingv1.NetworkPolicyPeer{{
+					PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"cluster-name": "mycluster"}},
+				}},
+			}},
+		},
+	}
+	cluster := &datav1.Postgres{ObjectMeta: metav1.ObjectMeta{Name: "mycluster", Namespace: app.GetNamespace()}}
+
+	n := &Synchronizer{config: config.Config{Features: config.Features{PostgresOperator: true}}}
+	check := func(app *nais_io_v1alpha1.Application, objects ...runtime.Object) error {
+		kube := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objects...).Build()
+		return n.checkDestructiveChanges(context.Background(), app, kube)
+	}
+
+	t.Run("switching cluster is blocked", func(t *testing.T) {
+		err := check(app, egress, cluster)
+		destructiveChanges := &ErrDestructiveChanges{}
+		assert.True(t, errors.As(err, &destructiveChanges))
+		assert.Equal(t, "deploy-1", destructiveChanges.CorrelationID)
+		assert.Equal(t, []string{`postgres cluster would be switched from "mycluster" to "othercluster"`}, destructiveChanges.Changes)
+	})
+
+	t.Run("acknowledged with the current correlation ID", func(t *testing.T) {
+		acknowledged := app.DeepCopy()
+		acknowledged.GetAnnotations()[annotations.AcknowledgeDestructiveChanges] = "deploy-1"
+		assert.NoError(t, check(acknowledged, egress, cluster))
+
+		// A new deployment must be acknowledged again.
+		acknowledged.GetAnnotations()[annotations.DeploymentCorrelationID] = "deploy-2"
+		assert.Error(t, check(acknowledged, egress, cluster))
+	})
+
+	t.Run("nothing is lost when the cluster is gone", func(t *testing.T) {
+		assert.NoError(t, check(app, egress))
+	})
+}
@@ -0,0 +1,51 @@
+package synchronizer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nais/naiserator/pkg/annotations"
+	"github.com/nais/naiserator/pkg/naiserator/config"
+	"github.com/nais/naiserator/pkg/resourcecreator/resource"
+)
+
+const (
+	// FreezeOverrideAnnotation lets a workload be rolled out during a freeze window.
+	// The value must describe the reason for the emergency rollout.
+	FreezeOverrideAnnotation = annotations.FreezeOverride
+	Frozen                   = "Frozen"
+	FreezeOverridden         = "FreezeOverridden"
+)
+
+// ErrFrozen is returned from Prepare when a rollout is held by a freeze window.
+type ErrFrozen struct {
+	Window        config.FreezeWindow
+	CorrelationID string
+}
+
+func (e *ErrFrozen) Error() string {
+	return fmt.Sprintf(
+		"rollout held by freeze window %q until %s; for emergencies, set the annotation %s to the reason for the rollout",
+		e.Window.Name, e.Window.End.Format(time.RFC3339), FreezeOverrideAnnotation,
+	)
+}
+
+// checkFreeze holds the rollout if a freeze window is active for the source's namespace.
+// If the freeze is overridden, a message for the audit trail is returned instead.
+func checkFreeze(cfg config.Config, source resource.Source, now time.Time) (string, error) {
+	window, active := cfg.ActiveFreezeWindow(source.GetNamespace(), now)
+	if !active {
+		return "", nil
+	}
+
+	reason := strings.TrimSpace(source.GetAnnotations()[FreezeOverrideAnnotation])
+	if len(reason) == 0 {
+		return "", &ErrFrozen{
+			Window:        window,
+			CorrelationID: source.CorrelationID(),
+		}
+	}
+
+	return fmt.Sprintf("Freeze window %q overridden: %s", window.Name, reason), nil
+}
@@ -0,0 +1,39 @@
+package synchronizer
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nais/naiserator/pkg/naiserator/config"
+	"github.com/nais/naiserator/pkg/test/fixtures"
+)
+
+func TestCheckFreeze(t *testing.T) {
+	now := time.Date(2026, 12, 24, 12, 0, 0, 0, time.UTC)
+	cfg := config.Config{
+		FreezeWindows: []config.FreezeWindow{
+			{
+				Name:  "christmas",
+				Start: time.Date(2026, 12, 20, 0, 0, 0, 0, time.UTC),
+				End:   time.Date(2027, 1, 2, 0, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	override, err := checkFreeze(config.Config{}, fixtures.MinimalApplication(), now)
+	assert.NoError(t, err)
+	assert.Empty(t, override)
+
+	_, err = checkFreeze(cfg, fixtures.MinimalApplication(), now)
+	frozen := &ErrFrozen{}
+	assert.True(t, errors.As(err, &frozen))
+	assert.Equal(t, "christmas", frozen.Window.Name)
+	assert.ErrorContains(t, err, `rollout held by freeze window "christmas" until 2027-01-02T00:00:00Z`)
+
+	override, err = checkFreeze(cfg, fixtures.MinimalApplication(fixtures.WithAnnotation(FreezeOverrideAnnotation, "fix for INC-123")), now)
+	assert.NoError(t, err)
+	assert.Equal(t, `Freeze window "christmas" overridden: fix for INC-123`, override)
+}
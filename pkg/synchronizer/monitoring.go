@@ -8,13 +8,16 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/nais/liberator/pkg/events"
+	"github.com/nais/naiserator/pkg/lifecycle"
 	"github.com/nais/naiserator/pkg/metrics"
 	"github.com/nais/naiserator/pkg/resourcecreator/batch"
 	"github.com/nais/naiserator/pkg/resourcecreator/resource"
+	"github.com/nais/naiserator/pkg/resourcecreator/statefulset"
 	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -22,6 +25,10 @@ import (
 const (
 	RolloutMessageCompleted        = "Rollout has completed"
 	RolloutMessageCronJobCompleted = "No support for monitoring CronJobs"
+
+	// RolloutFailed is the synchronization state of an application whose rollout exceeded the progress deadline of
+	// its Deployment, or did not complete within synchronizer.rollout-timeout.
+	RolloutFailed = "RolloutFailed"
 )
 
 var rolloutMonitorLock sync.Mutex
@@ -56,6 +63,12 @@ func (n *Synchronizer) MonitorRollout(app resource.Source, logger log.Entry) {
 	id := uuid.New()
 	ctx, cancel := context.WithCancel(context.Background())
 	rolloutMonitorLock.Lock()
+	if n.monitorsStopped {
+		// No longer the leader; the new leader resumes monitoring when it reconciles the application.
+		rolloutMonitorLock.Unlock()
+		cancel()
+		return
+	}
 	n.rolloutMonitor[objectKey] = RolloutMonitor{
 		id:     id,
 		cancel: cancel,
@@ -89,6 +102,31 @@ func (n *Synchronizer) cancelMonitor(objectKey client.ObjectKey, expected *uuid.
 	metrics.ResourcesMonitored.Set(float64(len(n.rolloutMonitor)))
 }
 
+// Start blocks until this replica stops being the leader, and then stops all rollout monitors,
+// so that rollouts are only reported by the current leader.
+// Rollouts are not lost on failover: the new leader reconciles every application on startup,
+// and resumes monitoring those that are synchronized but not yet rolled out.
+func (n *Synchronizer) Start(ctx context.Context) error {
+	<-ctx.Done()
+
+	rolloutMonitorLock.Lock()
+	defer rolloutMonitorLock.Unlock()
+
+	n.monitorsStopped = true
+	for objectKey, rollout := range n.rolloutMonitor {
+		rollout.cancel()
+		delete(n.rolloutMonitor, objectKey)
+	}
+	metrics.ResourcesMonitored.Set(0)
+
+	return nil
+}
+
+// NeedLeaderElection makes the manager start the synchronizer only on the leader.
+func (n *Synchronizer) NeedLeaderElection() bool {
+	return true
+}
+
 // Monitoring deployments to signal RolloutComplete.
 func (n *Synchronizer) monitorRolloutRoutine(ctx context.Context, app resource.Source, logger log.Entry) {
 	logger.Debugf("Monitoring rollout status")
@@ -99,6 +137,7 @@ func (n *Synchronizer) monitorRolloutRoutine(ctx context.Context, app resource.S
 	}
 
 	completion := completionState{}
+	deadline := time.Now().Add(n.config.Synchronizer.RolloutTimeout)
 
 	for {
 		select {
@@ -111,7 +150,7 @@ func (n *Synchronizer) monitorRolloutRoutine(ctx context.Context, app resource.S
 				}
 				return
 			default:
-				shouldContinue := n.monitorApplication(ctx, app, logger, objectKey, completion)
+				shouldContinue := n.monitorApplication(ctx, app, logger, objectKey, completion, deadline)
 				if shouldContinue {
 					continue
 				}
@@ -151,20 +190,30 @@ func (n *Synchronizer) monitorNaisjob(ctx context.Context, app resource.Source,
 	return false
 }
 
-// monitorApplication will only return false when all pods are successfully up and running. As long as we return true
-// we should keep monitoring the deployment.
-func (n *Synchronizer) monitorApplication(ctx context.Context, app resource.Source, logger log.Entry, objectKey client.ObjectKey, completion completionState) bool {
-	deploy := &appsv1.Deployment{}
-	err := n.Get(ctx, objectKey, deploy)
+// monitorApplication will only return false when all pods are successfully up and running, or the rollout has failed.
+// As long as we return true we should keep monitoring the deployment.
+func (n *Synchronizer) monitorApplication(ctx context.Context, app resource.Source, logger log.Entry, objectKey client.ObjectKey, completion completionState, deadline time.Time) bool {
+	complete, failure, err := n.applicationRolledOut(ctx, app, objectKey)
 	if err != nil {
 		if !errors.IsNotFound(err) {
-			logger.Errorf("Monitor rollout: failed to query Deployment: %v", err)
+			logger.Errorf("Monitor rollout: %v", err)
 		}
 		return true
 	}
 
-	if !applicationDeploymentComplete(deploy) {
-		return true
+	if !complete {
+		if len(failure) == 0 && time.Now().After(deadline) {
+			failure = fmt.Sprintf("Rollout has not completed within %s", n.config.Synchronizer.RolloutTimeout)
+		}
+		if len(failure) == 0 {
+			return true
+		}
+		err = n.failRolloutRoutine(ctx, app, logger, failure)
+		if err != nil {
+			logger.Errorf("Monitor rollout: %v", err)
+			return true
+		}
+		return false
 	}
 
 	err = n.completeRolloutRoutine(ctx, app, logger, completion, events.RolloutComplete, RolloutMessageCompleted)
@@ -186,6 +235,7 @@ func (n *Synchronizer) completeRolloutRoutine(ctx context.Context, app resource.
 		if err != nil {
 			return fmt.Errorf("unable to report rollout complete event: %v", err)
 		}
+		n.publishLifecycleEvent(lifecycle.TypeRolloutComplete, app, rolloutMessage, nil)
 	}
 
 	// Set the SynchronizationState field of the application to RolloutComplete.
@@ -209,6 +259,48 @@ func (n *Synchronizer) completeRolloutRoutine(ctx context.Context, app resource.
 	return nil
 }
 
+// failRolloutRoutine reports a rollout that won't complete by itself, and sets the synchronization state to
+// RolloutFailed, which is Stalled until the next deployment.
+func (n *Synchronizer) failRolloutRoutine(ctx context.Context, app resource.Source, logger log.Entry, rolloutMessage string) error {
+	_, err := n.reportEvent(ctx, resource.CreateEvent(app, RolloutFailed, rolloutMessage, "Warning"))
+	if err != nil {
+		return fmt.Errorf("unable to report rollout failed event: %v", err)
+	}
+	n.publishLifecycleEvent(lifecycle.TypeRolloutFailed, app, rolloutMessage, nil)
+
+	err = n.UpdateResource(ctx, app, func(app resource.Source) error {
+		setSynchronizationState(app, RolloutFailed, rolloutMessage)
+		// Use Status().Update() to avoid triggering mutating webhooks
+		return n.Status().Update(ctx, app)
+	})
+	if err != nil {
+		return fmt.Errorf("store application sync status: %v", err)
+	}
+
+	logger.Warnf("Rollout failed: %s; terminating monitoring", rolloutMessage)
+	return nil
+}
+
+// applicationRolledOut checks the Deployment or StatefulSet of the application, depending on its workload mode.
+// If the rollout has failed, the reason is returned.
+func (n *Synchronizer) applicationRolledOut(ctx context.Context, app resource.Source, objectKey client.ObjectKey) (bool, string, error) {
+	if statefulset.Enabled(app) {
+		statefulSet := &appsv1.StatefulSet{}
+		err := n.Get(ctx, objectKey, statefulSet)
+		if err != nil {
+			return false, "", fmt.Errorf("failed to query StatefulSet: %w", err)
+		}
+		return applicationStatefulSetComplete(statefulSet), "", nil
+	}
+
+	deploy := &appsv1.Deployment{}
+	err := n.Get(ctx, objectKey, deploy)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to query Deployment: %w", err)
+	}
+	return applicationDeploymentComplete(deploy), applicationDeploymentFailure(deploy), nil
+}
+
 // applicationDeploymentComplete considers a deployment to be complete once all of its desired replicas
 // are updated and available, and no old pods are running.
 //
@@ -221,8 +313,29 @@ func applicationDeploymentComplete(deployment *appsv1.Deployment) bool {
 		deployment.Status.ObservedGeneration >= deployment.Generation
 }
 
+// applicationDeploymentFailure returns the message of the Progressing condition if the deployment has exceeded its
+// progress deadline, and an empty string otherwise.
+func applicationDeploymentFailure(deployment *appsv1.Deployment) string {
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing && condition.Status == corev1.ConditionFalse && condition.Reason == "ProgressDeadlineExceeded" {
+			return condition.Message
+		}
+	}
+	return ""
+}
+
+// applicationStatefulSetComplete considers a StatefulSet to be complete once all of its desired replicas
+// are updated to the latest revision and available.
+func applicationStatefulSetComplete(statefulSet *appsv1.StatefulSet) bool {
+	return statefulSet.Status.UpdatedReplicas == *(statefulSet.Spec.Replicas) &&
+		statefulSet.Status.Replicas == *(statefulSet.Spec.Replicas) &&
+		statefulSet.Status.AvailableReplicas == *(statefulSet.Spec.Replicas) &&
+		statefulSet.Status.CurrentRevision == statefulSet.Status.UpdateRevision &&
+		statefulSet.Status.ObservedGeneration >= statefulSet.Generation
+}
+
 func setSyncStatus(app resource.Source, synchronizationState string) resource.Source {
-	app.GetStatus().SetSynchronizationStateWithCondition(synchronizationState, "Successfully deployed.")
+	setSynchronizationState(app, synchronizationState, "Successfully deployed.")
 
 	metrics.Synchronizations.With(
 		prometheus.Labels{
@@ -0,0 +1,28 @@
+package synchronizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestApplicationDeploymentFailure(t *testing.T) {
+	deployment := &appsv1.Deployment{}
+	deployment.Status.Conditions = []appsv1.DeploymentCondition{{
+		Type:    appsv1.DeploymentProgressing,
+		Status:  corev1.ConditionTrue,
+		Reason:  "ReplicaSetUpdated",
+		Message: `ReplicaSet "myapp-5d9c7b" is progressing.`,
+	}}
+	assert.Empty(t, applicationDeploymentFailure(deployment))
+
+	deployment.Status.Conditions[0] = appsv1.DeploymentCondition{
+		Type:    appsv1.DeploymentProgressing,
+		Status:  corev1.ConditionFalse,
+		Reason:  "ProgressDeadlineExceeded",
+		Message: `ReplicaSet "myapp-5d9c7b" has timed out progressing.`,
+	}
+	assert.Equal(t, `ReplicaSet "myapp-5d9c7b" has timed out progressing.`, applicationDeploymentFailure(deployment))
+}
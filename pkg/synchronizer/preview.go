@@ -0,0 +1,66 @@
+package synchronizer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nais/naiserator/pkg/resourcecreator/resource"
+)
+
+// Reasons of the Preview condition and of the event reported when a preview expires.
+const (
+	PreviewActive  = "PreviewActive"
+	PreviewExpired = "PreviewExpired"
+)
+
+// setPreview reports when a preview expires in the Preview condition.
+func setPreview(app resource.Source, expiry time.Time) {
+	status := app.GetStatus()
+	if status.Conditions == nil {
+		status.Conditions = &[]metav1.Condition{}
+	}
+
+	meta.SetStatusCondition(status.Conditions, metav1.Condition{
+		Type:               ConditionPreview,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: app.GetGeneration(),
+		Reason:             PreviewActive,
+		Message:            fmt.Sprintf("Preview expires at %s", expiry.UTC().Format(time.RFC3339)),
+	})
+}
+
+// deleteExpiredPreview deletes a preview whose time to live has passed, and reports the expiry as an event.
+func (n *Synchronizer) deleteExpiredPreview(ctx context.Context, app resource.Source, expiry time.Time) error {
+	logger := log.WithFields(app.LogFields())
+	msg := fmt.Sprintf("Preview expired at %s; deleting it", expiry.UTC().Format(time.RFC3339))
+	logger.Info(msg)
+
+	event := resource.CreateEvent(app, PreviewExpired, msg, "Normal")
+	// The event must outlive the preview, which would otherwise garbage collect it.
+	event.OwnerReferences = nil
+	_, err := n.reportEvent(ctx, event)
+	if err != nil {
+		logger.Errorf("While creating an event for this preview, an error occurred: %s", err)
+	}
+
+	return client.IgnoreNotFound(n.Delete(ctx, app))
+}
+
+// requeueAt makes sure that the workload is reconciled again no later than the given time, if set.
+func requeueAt(result ctrl.Result, at time.Time) ctrl.Result {
+	if at.IsZero() {
+		return result
+	}
+	after := max(time.Until(at), time.Second)
+	if result.RequeueAfter == 0 || after < result.RequeueAfter {
+		result.RequeueAfter = after
+	}
+	return result
+}
@@ -0,0 +1,47 @@
+package synchronizer
+
+import (
+	"github.com/nais/naiserator/pkg/progressive"
+	"github.com/nais/naiserator/pkg/resourcecreator/resource"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RolledBack is the synchronization state of an Application whose canary failed, and which runs the previous version.
+const RolledBack = "RolledBack"
+
+// ProgressiveSource is implemented by generator options of Applications that are rolled out progressively.
+// The state is nil for regular rollouts.
+type ProgressiveSource interface {
+	GetProgressiveState() *progressive.State
+}
+
+func progressiveState(options any) *progressive.State {
+	source, ok := options.(ProgressiveSource)
+	if !ok {
+		return nil
+	}
+	return source.GetProgressiveState()
+}
+
+// setProgressiveDelivery reports the phase of a progressive rollout in the ProgressiveDelivery condition.
+// The condition is true while the rollout is in progress.
+func setProgressiveDelivery(app resource.Source, state progressive.State) {
+	status := app.GetStatus()
+	if status.Conditions == nil {
+		status.Conditions = &[]metav1.Condition{}
+	}
+
+	conditionStatus := metav1.ConditionTrue
+	if state.Phase.Done() {
+		conditionStatus = metav1.ConditionFalse
+	}
+
+	meta.SetStatusCondition(status.Conditions, metav1.Condition{
+		Type:               ConditionProgressiveDelivery,
+		Status:             conditionStatus,
+		ObservedGeneration: app.GetGeneration(),
+		Reason:             string(state.Phase),
+		Message:            state.Message,
+	})
+}
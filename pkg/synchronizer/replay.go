@@ -0,0 +1,39 @@
+package synchronizer
+
+import (
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nais/naiserator/pkg/replay"
+	"github.com/nais/naiserator/pkg/resourcecreator/resource"
+)
+
+// newReplayBundle starts recording the generator inputs if recording is enabled and the source asks for it.
+func (n *Synchronizer) newReplayBundle(source resource.Source) (*replay.Bundle, error) {
+	if len(n.config.RecordDirectory) == 0 {
+		return nil, nil
+	}
+
+	record, _ := strconv.ParseBool(source.GetAnnotations()[replay.RecordAnnotation])
+	if !record {
+		return nil, nil
+	}
+
+	return replay.NewBundle(source, n.liveConfig())
+}
+
+// saveReplayBundle writes the bundle to disk. Failing to record doesn't stop the rollout.
+func (n *Synchronizer) saveReplayBundle(bundle *replay.Bundle, source resource.Source) {
+	if bundle == nil {
+		return
+	}
+
+	path, err := bundle.Save(n.config.RecordDirectory, source)
+	if err != nil {
+		log.WithFields(source.LogFields()).Errorf("Recording replay bundle: %s", err)
+		return
+	}
+
+	log.WithFields(source.LogFields()).Infof("Recorded replay bundle to %s", path)
+}
@@ -11,4 +11,18 @@ type Rollout struct {
 	Options             any
 	CorrelationID       string
 	SynchronizationHash string
+	Warnings            []Warning
+	Degradations        []Degradation
+}
+
+// Warning is a non-blocking problem with the source, reported to the user along with the rollout.
+type Warning struct {
+	Reason  string
+	Message string
+}
+
+func (r *Rollout) addWarnings(reason string, messages []string) {
+	for _, message := range messages {
+		r.Warnings = append(r.Warnings, Warning{Reason: reason, Message: message})
+	}
 }
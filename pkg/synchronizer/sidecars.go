@@ -0,0 +1,41 @@
+package synchronizer
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/nais/naiserator/pkg/resourcecreator/resource"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SidecarImagesSelected is the reason of the SidecarImages condition.
+const SidecarImagesSelected = "SidecarImagesSelected"
+
+// SidecarImageSource is implemented by generator options that take sidecar images from a sidecar channel.
+type SidecarImageSource interface {
+	SidecarImages() (channel string, images map[string]string)
+}
+
+// setSidecarImages reports the sidecar channel and images used for the workload in the SidecarImages condition.
+func setSidecarImages(app resource.Source, channel string, images map[string]string) {
+	status := app.GetStatus()
+	if status.Conditions == nil {
+		status.Conditions = &[]metav1.Condition{}
+	}
+
+	sidecars := make([]string, 0, len(images))
+	for sidecar, image := range images {
+		sidecars = append(sidecars, fmt.Sprintf("%s=%s", sidecar, image))
+	}
+	slices.Sort(sidecars)
+
+	meta.SetStatusCondition(status.Conditions, metav1.Condition{
+		Type:               ConditionSidecarImages,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: app.GetGeneration(),
+		Reason:             SidecarImagesSelected,
+		Message:            fmt.Sprintf("Sidecar channel %s: %s", channel, strings.Join(sidecars, ", ")),
+	})
+}
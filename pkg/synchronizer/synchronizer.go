@@ -2,6 +2,7 @@ package synchronizer
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
 	"strings"
@@ -11,8 +12,14 @@ import (
 	iam_cnrm_cloud_google_com_v1beta1 "github.com/nais/liberator/pkg/apis/iam.cnrm.cloud.google.com/v1beta1"
 	nais_io_v1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
 	"github.com/nais/liberator/pkg/events"
+	"github.com/nais/naiserator/pkg/annotations"
+	"github.com/nais/naiserator/pkg/deprecation"
+	"github.com/nais/naiserator/pkg/lifecycle"
 	"github.com/nais/naiserator/pkg/metrics"
 	"github.com/nais/naiserator/pkg/naiserator/config"
+	"github.com/nais/naiserator/pkg/policy"
+	"github.com/nais/naiserator/pkg/preview"
+	"github.com/nais/naiserator/pkg/progressive"
 	"github.com/nais/naiserator/pkg/readonly"
 	"github.com/nais/naiserator/pkg/resourcecreator/google"
 	"github.com/nais/naiserator/pkg/resourcecreator/resource"
@@ -37,6 +44,10 @@ import (
 const (
 	prepareRetryInterval = time.Minute * 30
 	NaiseratorFinalizer  = "naiserator.nais.io/finalizer"
+	PolicyViolation      = "PolicyViolation"
+	DeprecatedInput      = "DeprecatedInput"
+	InvalidAnnotation    = "InvalidAnnotation"
+	Recreating           = "Recreating"
 )
 
 // Generator transform CRD objects such as Application, Naisjob into other kinds of Kubernetes resources.
@@ -53,12 +64,19 @@ type Generator interface {
 type Synchronizer struct {
 	client.Client
 	config         config.Config
+	live           *config.Live
 	generator      Generator
 	listers        []client.ObjectList
 	rolloutMonitor map[client.ObjectKey]RolloutMonitor
 	scheme         *runtime.Scheme
 	simpleClient   client.Client
 	listableCache  map[schema.GroupVersionKind]bool
+
+	// monitorsStopped is set when this replica is no longer the leader.
+	monitorsStopped bool
+	// ownerIndexed is set when the client reads from a cache with updater.OwnerUIDIndex.
+	ownerIndexed bool
+	lifecycle    *lifecycle.Publisher
 }
 
 func NewSynchronizer(
@@ -82,10 +100,45 @@ func NewSynchronizer(
 	}
 }
 
+// WithLiveConfig makes the synchronizer pick up reloadable settings, such as freeze windows, without a restart.
+func (n *Synchronizer) WithLiveConfig(live *config.Live) *Synchronizer {
+	n.live = live
+	return n
+}
+
+// WithOwnerIndex makes the synchronizer find owned resources using updater.OwnerUIDIndex,
+// which must be registered in the cache of the synchronizer's client.
+func (n *Synchronizer) WithOwnerIndex() *Synchronizer {
+	n.ownerIndexed = true
+	return n
+}
+
+// WithLifecycleEvents makes the synchronizer publish lifecycle transitions of its workloads.
+func (n *Synchronizer) WithLifecycleEvents(publisher *lifecycle.Publisher) *Synchronizer {
+	n.lifecycle = publisher
+	return n
+}
+
+func (n *Synchronizer) publishLifecycleEvent(eventType string, app resource.Source, message string, operations resource.Operations) {
+	if n.lifecycle == nil {
+		return
+	}
+	n.lifecycle.Publish(lifecycle.NewEvent(eventType, n.config.ClusterName, app, message, operations))
+}
+
+func (n *Synchronizer) liveConfig() config.Config {
+	if n.live != nil {
+		return n.live.Get()
+	}
+	return n.config
+}
+
 // Commit wraps a cluster operation function with extra fields
 type commit struct {
 	groupVersionKind schema.GroupVersionKind
 	fn               func() error
+	// integration is set if the resource belongs to an optional integration that is allowed to fail.
+	integration string
 }
 
 // Creates a Kubernetes event, or updates an existing one with an incremented counter
@@ -128,6 +181,19 @@ func (n *Synchronizer) reportError(ctx context.Context, eventSource string, err
 	}
 }
 
+// Reports non-blocking problems through the workload status and Kubernetes warning events.
+func (n *Synchronizer) reportWarnings(ctx context.Context, warnings []Warning, source resource.Source) {
+	logger := log.WithFields(source.LogFields())
+	for _, warning := range warnings {
+		logger.Warn(warning.Message)
+		source.GetStatus().SetWarning(warning.Message)
+		_, err := n.reportEvent(ctx, resource.CreateEvent(source, warning.Reason, warning.Message, "Warning"))
+		if err != nil {
+			logger.Errorf("While creating an event for this warning, another error occurred: %s", err)
+		}
+	}
+}
+
 // Reconcile processes the work queue
 func (n *Synchronizer) Reconcile(ctx context.Context, req ctrl.Request, app resource.Source) (ctrl.Result, error) {
 	ctx, cancel := context.WithTimeout(ctx, n.config.Synchronizer.SynchronizationTimeout)
@@ -141,9 +207,11 @@ func (n *Synchronizer) Reconcile(ctx context.Context, req ctrl.Request, app reso
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
-	// We have some old, fun data in the conditions array from before. Zero them out for now to avoid confusion.
-	// fixme: can be removed when we don't have garbage data anymore. Late 2024?
-	app.GetStatus().Conditions = nil
+	// Workloads are also enqueued by watches on other kinds, such as Images, which can't be filtered by the
+	// workload's labels.
+	if !n.config.Shard.Owns(app.GetNamespace(), app.GetLabels()) {
+		return ctrl.Result{}, nil
+	}
 
 	// Clear out any old problems from previous synchronizations.
 	app.GetStatus().ClearProblems()
@@ -198,6 +266,23 @@ func (n *Synchronizer) Reconcile(ctx context.Context, req ctrl.Request, app reso
 	}()
 
 	if appIsDeleted(app) {
+		if controllerutil.ContainsFinalizer(app, NaiseratorFinalizer) {
+			lost, err := n.heldForDeletion(ctx, app)
+			if err != nil {
+				// Deletion is neither held nor allowed until the stateful resources are known.
+				setSynchronizationState(app, events.Retrying, err.Error())
+				n.reportError(ctx, app.GetStatus().SynchronizationState, err, app)
+				return ctrl.Result{}, err
+			}
+			if len(lost) > 0 {
+				// Adding the confirmation annotation triggers a new reconcile.
+				msg := deletionHeldMessage(lost)
+				setSynchronizationState(app, DeletionBlocked, msg)
+				n.reportWarnings(ctx, []Warning{{Reason: DeletionBlocked, Message: msg}}, app)
+				return ctrl.Result{}, nil
+			}
+		}
+
 		err = n.cleanUpAfterAppDeletion(ctx, app)
 		if err != nil {
 			return ctrl.Result{}, err
@@ -209,6 +294,7 @@ func (n *Synchronizer) Reconcile(ctx context.Context, req ctrl.Request, app reso
 			"gvk":       app.GetObjectKind().GroupVersionKind().String(),
 		})
 		logger.Infof("Application has been deleted from Kubernetes")
+		n.publishLifecycleEvent(lifecycle.TypeWorkloadDeleted, app, "Workload has been deleted", nil)
 
 		changed = false // don't run update after deletion
 		return ctrl.Result{}, nil
@@ -224,12 +310,38 @@ func (n *Synchronizer) Reconcile(ctx context.Context, req ctrl.Request, app reso
 		}
 	}
 
+	var previewExpiry time.Time
+	if preview.Enabled(app.GetAnnotations()) {
+		previewExpiry = preview.Expiry(app.GetAnnotations(), app.GetCreationTimestamp().Time, n.liveConfig().Preview)
+		if !time.Now().Before(previewExpiry) {
+			changed = false // the finalizer cleans up once the preview is deleted
+			return ctrl.Result{}, n.deleteExpiredPreview(ctx, app, previewExpiry)
+		}
+		setPreview(app, previewExpiry)
+	}
+
 	// Prepare configuration
 	rollout, err := n.Prepare(ctx, app)
-	if err != nil {
-		app.GetStatus().SetSynchronizationStateWithCondition(events.FailedPrepare, err.Error())
+	destructiveChanges := &ErrDestructiveChanges{}
+	frozen := &ErrFrozen{}
+	if errors.As(err, &destructiveChanges) {
+		// The user must acknowledge the changes by annotating the resource, which triggers a new reconcile.
+		setSynchronizationState(app, DestructiveChangesBlocked, err.Error())
 		app.GetStatus().SetError(err.Error())
+		app.GetStatus().CorrelationID = destructiveChanges.CorrelationID
 		n.reportError(ctx, app.GetStatus().SynchronizationState, err, app)
+		return ctrl.Result{}, nil
+	} else if errors.As(err, &frozen) {
+		// Try again when the window ends, unless an override annotation triggers a reconcile first.
+		setSynchronizationState(app, Frozen, err.Error())
+		app.GetStatus().CorrelationID = frozen.CorrelationID
+		n.reportWarnings(ctx, []Warning{{Reason: Frozen, Message: err.Error()}}, app)
+		return ctrl.Result{RequeueAfter: time.Until(frozen.Window.End)}, nil
+	} else if err != nil {
+		setSynchronizationState(app, events.FailedPrepare, err.Error())
+		app.GetStatus().SetError(err.Error())
+		n.reportError(ctx, app.GetStatus().SynchronizationState, err, app)
+		n.publishLifecycleEvent(lifecycle.TypeRolloutFailed, app, err.Error(), nil)
 		return ctrl.Result{RequeueAfter: prepareRetryInterval}, nil
 	}
 
@@ -242,34 +354,60 @@ func (n *Synchronizer) Reconcile(ctx context.Context, req ctrl.Request, app reso
 			n.MonitorRollout(app, logger)
 		}
 
-		return ctrl.Result{}, nil
+		return requeueAt(ctrl.Result{}, previewExpiry), nil
 	}
 
+	n.reportWarnings(ctx, rollout.Warnings, app)
+
 	// Generate the actual Kubernetes resources that are going out into the cluster
 	rollout.ResourceOperations, err = n.generator.Generate(rollout.Source, rollout.Options)
 	if err != nil {
-		app.GetStatus().SetSynchronizationStateWithCondition(events.FailedGenerate, err.Error())
+		setSynchronizationState(app, events.FailedGenerate, err.Error())
 		app.GetStatus().SetError(err.Error())
 		n.reportError(ctx, app.GetStatus().SynchronizationState, err, app)
+		n.publishLifecycleEvent(lifecycle.TypeRolloutFailed, app, err.Error(), nil)
 		return ctrl.Result{}, err
 	}
 
+	if degradable, ok := rollout.Options.(Degradable); ok {
+		rollout.Degradations = append(rollout.Degradations, degradable.Degradations()...)
+	}
+
 	logger = *log.WithFields(app.LogFields())
 	logger.Debugf("Starting synchronization")
 
+	progress := progressiveState(rollout.Options)
+	inProgress := progress != nil && !progress.Phase.Done()
+	// Progressive rollouts are checked every progressive.CheckInterval; events, lifecycle events and status
+	// are only written when the phase or step changes.
+	quiet := inProgress && !progress.Changed
+
 	app.GetStatus().CorrelationID = rollout.CorrelationID
+	if !quiet {
+		n.publishLifecycleEvent(lifecycle.TypeSyncStarted, app, "Synchronization started", rollout.ResourceOperations)
+	}
 
-	retry, err := n.Sync(ctx, *rollout)
-	if err != nil {
+	retry, err := n.Sync(ctx, rollout)
+	recreating := &updater.ErrRecreatePending{}
+	if errors.As(err, &recreating) {
+		// Synchronization continues once the old resource is gone; the hash is not saved until then.
+		setSynchronizationState(app, Recreating, err.Error())
+		_, err = n.reportEvent(ctx, resource.CreateEvent(app, Recreating, err.Error(), "Normal"))
+		if err != nil {
+			logger.Errorf("While creating an event for this rollout, an error occurred: %s", err)
+		}
+		return ctrl.Result{RequeueAfter: n.liveConfig().Synchronizer.RolloutCheckInterval}, nil
+	} else if err != nil {
 		if retry {
-			app.GetStatus().SetSynchronizationStateWithCondition(events.Retrying, err.Error())
+			setSynchronizationState(app, events.Retrying, err.Error())
 			// No error in problems array, because this is a transient error which the user has no control over.
 			n.reportError(ctx, app.GetStatus().SynchronizationState, err, app)
 		} else {
-			app.GetStatus().SetSynchronizationStateWithCondition(events.FailedSynchronization, err.Error())
+			setSynchronizationState(app, events.FailedSynchronization, err.Error())
 			app.GetStatus().SetError(err.Error())
 			app.GetStatus().SynchronizationHash = rollout.SynchronizationHash // permanent failure
 			n.reportError(ctx, app.GetStatus().SynchronizationState, err, app)
+			n.publishLifecycleEvent(lifecycle.TypeRolloutFailed, app, err.Error(), rollout.ResourceOperations)
 			err = nil
 		}
 		return ctrl.Result{}, err
@@ -278,19 +416,69 @@ func (n *Synchronizer) Reconcile(ctx context.Context, req ctrl.Request, app reso
 	// Synchronization OK
 	syncMsg := "Deployment has been processed; waiting for completion..."
 	logger.Debugf("Successful synchronization")
-	app.GetStatus().SetSynchronizationStateWithCondition(events.Synchronized, syncMsg)
-	app.GetStatus().SynchronizationHash = rollout.SynchronizationHash
+	setSynchronizationState(app, events.Synchronized, syncMsg)
+	setDegraded(app, rollout.Degradations)
+	if sidecars, ok := rollout.Options.(SidecarImageSource); ok {
+		channel, images := sidecars.SidecarImages()
+		setSidecarImages(app, channel, images)
+	}
+	if !quiet {
+		n.publishLifecycleEvent(lifecycle.TypeResourcesApplied, app, syncMsg, rollout.ResourceOperations)
+	}
 	app.GetStatus().SynchronizationTime = time.Now().UnixNano()
 
+	result := ctrl.Result{}
+	if len(rollout.Degradations) > 0 {
+		// Leave the hash unset, so that the failing integrations are retried.
+		msg := degradationMessage(rollout.Degradations)
+		logger.Warnf("Synchronized with degraded integrations: %s", msg)
+		n.reportWarnings(ctx, []Warning{{Reason: Degraded, Message: msg}}, app)
+		result.RequeueAfter = prepareRetryInterval
+	}
+	if inProgress && (result.RequeueAfter == 0 || progress.RequeueAfter < result.RequeueAfter) {
+		// Leave the hash unset, so that the next step of the rollout is prepared when requeued.
+		result.RequeueAfter = progress.RequeueAfter
+	}
+	if len(rollout.Degradations) == 0 && !inProgress {
+		app.GetStatus().SynchronizationHash = rollout.SynchronizationHash
+	}
+
+	if progress != nil {
+		setProgressiveDelivery(app, *progress)
+		if progress.Phase != progressive.PhasePromoted {
+			// The stable Deployment doesn't run the new version, so a rollout monitor would report completion too early.
+			n.cancelMonitor(client.ObjectKey{Name: app.GetName(), Namespace: app.GetNamespace()}, nil)
+		}
+		if progress.Phase == progressive.PhaseRolledBack {
+			setSynchronizationState(app, RolledBack, progress.Message)
+			app.GetStatus().SetError(progress.Message)
+			n.reportWarnings(ctx, []Warning{{Reason: RolledBack, Message: progress.Message}}, app)
+			n.publishLifecycleEvent(lifecycle.TypeRolloutFailed, app, progress.Message, rollout.ResourceOperations)
+			return result, nil
+		}
+		syncMsg = progress.Message
+		setSynchronizationState(app, events.Synchronized, syncMsg)
+	}
+
+	if quiet {
+		changed = false // the status already shows this step
+		return result, nil
+	}
+
 	_, err = n.reportEvent(ctx, resource.CreateEvent(app, app.GetStatus().SynchronizationState, syncMsg, "Normal"))
 	if err != nil {
 		log.Errorf("While creating an event for this rollout, an error occurred: %s", err)
 	}
 
+	if inProgress {
+		// The rollout is monitored once the new version runs in the stable Deployment.
+		return result, nil
+	}
+
 	// Monitor the rollout status so that we can report a successfully completed rollout to NAIS deploy.
 	n.MonitorRollout(app, logger)
 
-	return ctrl.Result{}, nil
+	return requeueAt(result, previewExpiry), nil
 }
 
 func (n *Synchronizer) cleanUpAfterAppDeletion(ctx context.Context, app resource.Source) error {
@@ -305,6 +493,8 @@ func (n *Synchronizer) cleanUpAfterAppDeletion(ctx context.Context, app resource
 			return err
 		}
 
+		deprecation.Forget(app.GetObjectKind().GroupVersionKind().Kind, app.GetNamespace(), app.GetName())
+
 		controllerutil.RemoveFinalizer(app, NaiseratorFinalizer)
 		err = n.Update(ctx, app)
 		if err != nil {
@@ -397,6 +587,7 @@ func (n *Synchronizer) deleteImageResource(ctx context.Context, src resource.Sou
 }
 
 // Unreferenced return all resources in cluster which was created by synchronizer previously, but is not included in the current rollout.
+// Resources of integrations that were skipped in this rollout are kept.
 func (n *Synchronizer) Unreferenced(ctx context.Context, rollout Rollout) ([]runtime.Object, error) {
 	// Return true if a cluster resource also is applied with the rollout.
 	intersects := func(existing runtime.Object) bool {
@@ -423,24 +614,46 @@ func (n *Synchronizer) Unreferenced(ctx context.Context, rollout Rollout) ([]run
 		return false
 	}
 
-	resources, err := updater.FindAll(ctx, n.Client, n.listers, rollout.Source)
+	find := updater.FindAll
+	if n.ownerIndexed {
+		find = updater.FindOwned
+	}
+
+	resources, err := find(ctx, n.Client, n.listers, rollout.Source)
 	if err != nil {
 		return nil, fmt.Errorf("discovering unreferenced resources: %s", err)
 	}
 
 	unreferenced := make([]runtime.Object, 0, len(resources))
 	for _, existing := range resources {
-		if !intersects(existing) {
-			unreferenced = append(unreferenced, existing)
+		if intersects(existing) {
+			continue
+		}
+		gvk, err := apiutil.GVKForObject(existing, n.scheme)
+		if err == nil && degradedIntegration(rollout.Degradations, gvk) {
+			log.Debugf("Keeping %s %s, as its integration is degraded", gvk.Kind, existing.(client.Object).GetName())
+			continue
 		}
+		unreferenced = append(unreferenced, existing)
 	}
 
 	return unreferenced, nil
 }
 
-func (n *Synchronizer) rolloutWithRetryAndMetrics(commits []commit) (bool, error) {
+func (n *Synchronizer) rolloutWithRetryAndMetrics(rollout *Rollout, commits []commit) (bool, error) {
 	for _, commit := range commits {
 		if err := observeDuration(commit.fn); err != nil {
+			recreating := &updater.ErrRecreatePending{}
+			if errors.As(err, &recreating) {
+				return false, err
+			}
+			if len(commit.integration) > 0 {
+				rollout.Degradations = append(rollout.Degradations, Degradation{
+					Integration: commit.integration,
+					Message:     fmt.Sprintf("persisting %s to Kubernetes: %s", commit.groupVersionKind.Kind, err),
+				})
+				continue
+			}
 			retry := false
 			// In case of race condition errors
 			if k8s_errors.IsConflict(err) {
@@ -457,9 +670,11 @@ func (n *Synchronizer) rolloutWithRetryAndMetrics(commits []commit) (bool, error
 	return false, nil
 }
 
-func (n *Synchronizer) Sync(ctx context.Context, rollout Rollout) (bool, error) {
-	commits := n.ClusterOperations(ctx, rollout)
-	return n.rolloutWithRetryAndMetrics(commits)
+// Sync commits the rollout to the cluster.
+// Failures in optional integrations with soft-fail enabled are added to the rollout's degradations.
+func (n *Synchronizer) Sync(ctx context.Context, rollout *Rollout) (bool, error) {
+	commits := n.ClusterOperations(ctx, *rollout)
+	return n.rolloutWithRetryAndMetrics(rollout, commits)
 }
 
 // Prepare converts a NAIS application spec into a Rollout object.
@@ -472,6 +687,14 @@ func (n *Synchronizer) Prepare(ctx context.Context, source resource.Source) (*Ro
 		Source: source,
 	}
 
+	// Deprecated inputs must be detected before default values are filled in.
+	deprecations := deprecation.Detect(source)
+	deprecation.Report(source.GetObjectKind().GroupVersionKind().Kind, source.GetNamespace(), source.GetName(), deprecations)
+	rollout.addWarnings(DeprecatedInput, deprecation.Messages(deprecations))
+
+	// Without the admission webhook, invalid annotation values are only reported; the generators fall back to defaults.
+	rollout.addWarnings(InvalidAnnotation, annotations.Validate(source.GetAnnotations()).Messages())
+
 	err = source.ApplyDefaults()
 	if err != nil {
 		return nil, fmt.Errorf("BUG: merge default values into application: %s", err)
@@ -501,14 +724,44 @@ func (n *Synchronizer) Prepare(ctx context.Context, source resource.Source) (*Ro
 
 	updateEffectiveImage(source, wantedImage)
 
+	violations := policy.Evaluate(n.config.PolicyRules, policy.NewWorkload(source))
+	err = violations.Err()
+	if err != nil {
+		return nil, fmt.Errorf("policy violation: %w", err)
+	}
+	rollout.addWarnings(PolicyViolation, violations.Warnings().Messages())
+
 	err = ensureCorrelationID(source)
 	if err != nil {
 		return nil, err
 	}
 
+	override, err := checkFreeze(n.liveConfig(), source, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if len(override) > 0 {
+		rollout.addWarnings(FreezeOverridden, []string{override})
+	}
+
+	err = n.checkDestructiveChanges(ctx, source, readOnlyClient)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle, err := n.newReplayBundle(source)
+	if err != nil {
+		return nil, err
+	}
+	prepareClient := readOnlyClient
+	if bundle != nil {
+		prepareClient = bundle.RecordingClient(readOnlyClient)
+	}
+
 	// Prepare for rollout (i.e. use cluster information to generate a configuration object).
 	// For this operation, make sure that write operations are disabled.
-	opts, err := n.generator.Prepare(ctx, source, readOnlyClient)
+	opts, err := n.generator.Prepare(ctx, source, prepareClient)
+	n.saveReplayBundle(bundle, source)
 	if err != nil {
 		return nil, fmt.Errorf("preparing rollout configuration: %w", err)
 	}
@@ -532,11 +785,13 @@ func (n *Synchronizer) ClusterOperations(ctx context.Context, rollout Rollout) [
 		return o.GetObjectKind().GroupVersionKind()
 	}
 
+	cfg := n.liveConfig()
 	for _, rop := range rollout.ResourceOperations {
 		c := commit{
 			groupVersionKind: getGroupVersionKind(rop.Resource),
 		}
 		n.checkListable(c.groupVersionKind)
+		c.integration = softFailIntegration(cfg, rollout.Source, c.groupVersionKind)
 		switch rop.Operation {
 		case resource.OperationCreateOrUpdate:
 			c.fn = updater.CreateOrUpdate(ctx, n.Client, n.scheme, rop.Resource)
@@ -569,9 +824,11 @@ func (n *Synchronizer) ClusterOperations(ctx context.Context, rollout Rollout) [
 		}})
 	} else {
 		for _, rsrc := range unreferenced {
+			gvk := getGroupVersionKind(rsrc)
 			deletes = append(deletes, commit{
-				groupVersionKind: getGroupVersionKind(rsrc),
+				groupVersionKind: gvk,
 				fn:               updater.DeleteIfExists(ctx, n.Client, rsrc.(client.Object)),
+				integration:      softFailIntegration(cfg, rollout.Source, gvk),
 			})
 		}
 	}
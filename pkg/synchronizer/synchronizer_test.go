@@ -13,6 +13,7 @@ import (
 	nais_io_v1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
 	nais_io_v1alpha1 "github.com/nais/liberator/pkg/apis/nais.io/v1alpha1"
 	sql_cnrm_cloud_google_com_v1beta1 "github.com/nais/liberator/pkg/apis/sql.cnrm.cloud.google.com/v1beta1"
+	storage_cnrm_cloud_google_com_v1beta1 "github.com/nais/liberator/pkg/apis/storage.cnrm.cloud.google.com/v1beta1"
 	"github.com/nais/liberator/pkg/crd"
 	"github.com/nais/liberator/pkg/events"
 	liberator_scheme "github.com/nais/liberator/pkg/scheme"
@@ -226,6 +227,10 @@ func TestSynchronizer(t *testing.T) {
 		testDeleteCorrectIAMResources(t, rig, ctx, app)
 	})
 
+	t.Run("Deletion Protection", func(t *testing.T) {
+		testDeletionProtection(t, rig, ctx)
+	})
+
 	t.Run("App With External Image Deployment", func(t *testing.T) {
 		// Ensure that external image resource exists
 		image := fixtures.MinimalImage(fixtures.WithName(fixtures.OtherApplicationName))
@@ -443,6 +448,67 @@ func testDeleteCorrectIAMResources(t *testing.T, rig *testRig, ctx context.Conte
 	assert.Equal(t, app.GetName(), iam_service_accounts.Items[0].Labels["app"])
 }
 
+func testDeletionProtection(t *testing.T, rig *testRig, ctx context.Context) {
+	app := fixtures.MinimalApplication(
+		fixtures.WithName("protected"),
+		fixtures.WithAnnotation(nais_io.DeploymentCorrelationIDAnnotation, "deploy-id-protected"),
+		fixtures.WithAnnotation(synchronizer.DeletionProtectionAnnotation, "true"),
+	)
+	err := rig.client.Create(ctx, app)
+	require.NoError(t, err)
+
+	req := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: app.Namespace,
+			Name:      app.Name,
+		},
+	}
+
+	// Reconcile for finalizer, then to be synchronized
+	for range 2 {
+		_, err = rig.synchronizer.Reconcile(ctx, req)
+		require.NoError(t, err)
+	}
+
+	err = rig.client.Get(ctx, req.NamespacedName, app)
+	require.NoError(t, err)
+
+	bucketMeta := resource.CreateObjectMeta(app)
+	bucketMeta.Name = "protected-bucket"
+	bucket := &storage_cnrm_cloud_google_com_v1beta1.StorageBucket{
+		ObjectMeta: bucketMeta,
+		Spec: storage_cnrm_cloud_google_com_v1beta1.StorageBucketSpec{
+			Location: google.Region,
+		},
+	}
+	err = rig.client.Create(ctx, bucket)
+	require.NoError(t, err)
+
+	// Deletion is held while the application owns a bucket that will be deleted along with its contents
+	err = rig.client.Delete(ctx, app)
+	require.NoError(t, err)
+
+	result, err := rig.synchronizer.Reconcile(ctx, req)
+	assert.NoError(t, err)
+	assert.Equal(t, ctrl.Result{}, result)
+
+	err = rig.client.Get(ctx, req.NamespacedName, app)
+	require.NoError(t, err)
+	assert.Equal(t, synchronizer.DeletionBlocked, app.Status.SynchronizationState)
+
+	// Confirming the deletion releases the finalizer
+	app.Annotations[synchronizer.ConfirmDeletionAnnotation] = "true"
+	err = rig.client.Update(ctx, app)
+	require.NoError(t, err)
+
+	result, err = rig.synchronizer.Reconcile(ctx, req)
+	assert.NoError(t, err)
+	assert.Equal(t, ctrl.Result{}, result)
+
+	err = rig.client.Get(ctx, req.NamespacedName, app)
+	assert.True(t, errors.IsNotFound(err))
+}
+
 func TestSynchronizerResourceOptions(t *testing.T) {
 	ctx := t.Context()
 	cfg := config.Config{
@@ -0,0 +1,147 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	nais_io "github.com/nais/liberator/pkg/apis/nais.io"
+	nais_io_v1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/nais/naiserator/pkg/annotations"
+	"github.com/nais/naiserator/pkg/generators"
+	"github.com/nais/naiserator/pkg/naiserator/config"
+	"github.com/nais/naiserator/pkg/resourcecreator/resource"
+)
+
+const (
+	MutatorPath = "/mutate-naiserator"
+
+	// DeployTimestampAnnotation is set to the time of admission whenever a workload gets a new correlation ID,
+	// which happens once per deploy.
+	DeployTimestampAnnotation = annotations.DeployTimestamp
+)
+
+// Mutator fills in deploy metadata and cluster-specific defaults on Applications and Naisjobs when they are stored,
+// so that the stored object shows what will actually be generated.
+// Spec defaults are left to the CRD schema and the liberator webhooks; deprecated inputs must still be visible
+// to the Validator. Naiserator falls back to the same defaults when synchronizing, in case the webhook is not installed.
+type Mutator struct {
+	Decoder admission.Decoder
+	Client  client.Reader
+	Config  config.Config
+	// Live, when set, takes precedence over Config so that reloaded configuration is used.
+	Live *config.Live
+}
+
+var _ admission.Handler = &Mutator{}
+
+func (m *Mutator) config() config.Config {
+	if m.Live != nil {
+		return m.Live.Get()
+	}
+	return m.Config
+}
+
+type observabilitySource interface {
+	GetObservability() *nais_io_v1.Observability
+}
+
+func (m *Mutator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+
+	source, err := decode(m.Decoder, req.Kind.Kind, req.Object)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	previousCorrelationID := ""
+	if req.Operation == admissionv1.Update {
+		previous, err := decode(m.Decoder, req.Kind.Kind, req.OldObject)
+		if err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		previousCorrelationID = previous.GetAnnotations()[nais_io.DeploymentCorrelationIDAnnotation]
+	}
+
+	namespace := &corev1.Namespace{}
+	err = m.Client.Get(ctx, client.ObjectKey{Name: req.Namespace}, namespace)
+	if err != nil && !errors.IsNotFound(err) {
+		return admission.Errored(http.StatusInternalServerError, fmt.Errorf("query namespace: %w", err))
+	}
+
+	setDeployMetadata(source, previousCorrelationID, time.Now())
+	setTeamLabel(source)
+	m.setLoggingDestination(source, namespace)
+
+	mutated, err := json.Marshal(source)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	return admission.PatchResponseFromRaw(req.Object.Raw, mutated)
+}
+
+// setDeployMetadata gives the workload a correlation ID if it doesn't have one,
+// and records the deploy timestamp every time the correlation ID changes.
+func setDeployMetadata(source resource.Source, previousCorrelationID string, now time.Time) {
+	annotations := source.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+
+	if len(annotations[nais_io.DeploymentCorrelationIDAnnotation]) == 0 {
+		annotations[nais_io.DeploymentCorrelationIDAnnotation] = uuid.NewString()
+	}
+
+	if annotations[nais_io.DeploymentCorrelationIDAnnotation] != previousCorrelationID || len(annotations[DeployTimestampAnnotation]) == 0 {
+		annotations[DeployTimestampAnnotation] = now.UTC().Format(time.RFC3339)
+	}
+
+	source.SetAnnotations(annotations)
+}
+
+// setTeamLabel sets the team label to the namespace name, which is what Naiserator sets on the workload
+// and every generated resource when synchronizing.
+func setTeamLabel(source resource.Source) {
+	labels := source.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+
+	labels["team"] = source.GetNamespace()
+
+	source.SetLabels(labels)
+}
+
+// setLoggingDestination fills in the default logging destination for the cluster or namespace
+// when logging is enabled without any destinations, using the same default as the generators.
+func (m *Mutator) setLoggingDestination(source resource.Source, namespace *corev1.Namespace) {
+	workload, ok := source.(observabilitySource)
+	if !ok {
+		return
+	}
+
+	observability := workload.GetObservability()
+	if observability == nil || observability.Logging == nil || !observability.Logging.Enabled || len(observability.Logging.Destinations) > 0 {
+		return
+	}
+
+	// Invalid namespace defaults are left for the synchronizer to report.
+	destination, err := generators.DefaultLoggingDestination(m.config(), namespace)
+	if err != nil || len(destination) == 0 {
+		return
+	}
+
+	observability.Logging.Destinations = []nais_io_v1.LogDestination{{ID: destination}}
+}
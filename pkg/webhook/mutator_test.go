@@ -0,0 +1,78 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	nais_io "github.com/nais/liberator/pkg/apis/nais.io"
+	nais_io_v1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/nais/naiserator/pkg/generators"
+	"github.com/nais/naiserator/pkg/naiserator/config"
+	"github.com/nais/naiserator/pkg/test/fixtures"
+)
+
+func TestSetDeployMetadata(t *testing.T) {
+	deployed := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	app := fixtures.MinimalApplication(fixtures.WithAnnotation(nais_io.DeploymentCorrelationIDAnnotation, "deploy-1"))
+	setDeployMetadata(app, "", deployed)
+	assert.Equal(t, "2026-10-19T12:00:00Z", app.GetAnnotations()[DeployTimestampAnnotation])
+
+	// Updates within the same deploy keep the timestamp.
+	setDeployMetadata(app, "deploy-1", deployed.Add(time.Hour))
+	assert.Equal(t, "2026-10-19T12:00:00Z", app.GetAnnotations()[DeployTimestampAnnotation])
+
+	// Workloads without a correlation ID get one.
+	app = fixtures.MinimalApplication()
+	setDeployMetadata(app, "", deployed)
+	assert.NotEmpty(t, app.GetAnnotations()[nais_io.DeploymentCorrelationIDAnnotation])
+}
+
+func TestSetTeamLabel(t *testing.T) {
+	app := fixtures.MinimalApplication()
+	setTeamLabel(app)
+	assert.Equal(t, app.GetNamespace(), app.GetLabels()["team"])
+
+	// The synchronizer always sets the team label to the namespace name.
+	app = fixtures.MinimalApplication()
+	app.SetLabels(map[string]string{"team": "owners"})
+	setTeamLabel(app)
+	assert.Equal(t, app.GetNamespace(), app.GetLabels()["team"])
+}
+
+func TestSetLoggingDestination(t *testing.T) {
+	mutator := &Mutator{
+		Config: config.Config{
+			Observability: config.Observability{
+				Logging: config.Logging{
+					DefaultDestination: "loki",
+					Destinations:       []string{"loki", "elastic"},
+				},
+			},
+		},
+	}
+
+	app := fixtures.MinimalApplication()
+	app.Spec.Observability = &nais_io_v1.Observability{Logging: &nais_io_v1.Logging{Enabled: true}}
+	mutator.setLoggingDestination(app, &corev1.Namespace{})
+	assert.Equal(t, []nais_io_v1.LogDestination{{ID: "loki"}}, app.Spec.Observability.Logging.Destinations)
+
+	app.Spec.Observability.Logging.Destinations = nil
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Annotations: map[string]string{generators.NamespaceDefaultLoggingDestination: "elastic"},
+	}}
+	mutator.setLoggingDestination(app, namespace)
+	assert.Equal(t, []nais_io_v1.LogDestination{{ID: "elastic"}}, app.Spec.Observability.Logging.Destinations)
+
+	// Reloaded configuration takes precedence.
+	reloaded := mutator.Config
+	reloaded.Observability.Logging.DefaultDestination = "elastic"
+	mutator.Live = config.NewLive(reloaded)
+	app.Spec.Observability.Logging.Destinations = nil
+	mutator.setLoggingDestination(app, &corev1.Namespace{})
+	assert.Equal(t, []nais_io_v1.LogDestination{{ID: "elastic"}}, app.Spec.Observability.Logging.Destinations)
+}
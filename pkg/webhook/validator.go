@@ -0,0 +1,153 @@
+// Package webhook contains admission handlers served by the naiserator webhook binary.
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	nais_io_v1 "github.com/nais/liberator/pkg/apis/nais.io/v1"
+	nais_io_v1alpha1 "github.com/nais/liberator/pkg/apis/nais.io/v1alpha1"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/nais/naiserator/pkg/annotations"
+	"github.com/nais/naiserator/pkg/deprecation"
+	"github.com/nais/naiserator/pkg/naiserator/config"
+	"github.com/nais/naiserator/pkg/policy"
+	"github.com/nais/naiserator/pkg/resourcecreator/resource"
+)
+
+const ValidatorPath = "/validate-naiserator"
+
+// Validator rejects Applications and Naisjobs that violate policy rules with severity "error",
+// or set invalid values for nais.io annotations. Policy violations with severity "warning", unknown nais.io
+// annotations and usage of deprecated inputs are returned as admission warnings.
+//
+// Updates that change neither the spec nor the annotations, such as naiserator adding or removing its finalizer,
+// and updates to workloads that are being deleted, are never rejected by policy rules. Otherwise, adding a rule that existing
+// workloads violate would stop those workloads from being reconciled or deleted.
+// For the same reason, invalid annotation values are only rejected when they are created or changed.
+type Validator struct {
+	Decoder admission.Decoder
+	Rules   []config.PolicyRule
+}
+
+var _ admission.Handler = &Validator{}
+
+func (v *Validator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+
+	source, err := decode(v.Decoder, req.Kind.Kind, req.Object)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if source.GetDeletionTimestamp() != nil {
+		return admission.Allowed("")
+	}
+
+	enforcePolicy := true
+	var previousAnnotations map[string]string
+	if req.Operation == admissionv1.Update {
+		previous, err := decode(v.Decoder, req.Kind.Kind, req.OldObject)
+		if err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		previousAnnotations = previous.GetAnnotations()
+
+		changed, err := workloadChanged(req.Object, req.OldObject)
+		if err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		enforcePolicy = changed
+	}
+
+	// Deprecated inputs must be detected before default values are filled in.
+	warnings := deprecation.Messages(deprecation.Detect(source))
+
+	problems := annotations.Validate(source.GetAnnotations())
+	warnings = append(warnings, problems.Unknown().Messages()...)
+
+	// Invalid values that were stored before are only reported, so that the workload can still be updated.
+	denied := make(annotations.Problems, 0)
+	for _, problem := range problems.Invalid() {
+		previous, found := previousAnnotations[problem.Key]
+		if found && previous == source.GetAnnotations()[problem.Key] {
+			warnings = append(warnings, problem.String())
+		} else {
+			denied = append(denied, problem)
+		}
+	}
+
+	err = denied.Err()
+	if err != nil {
+		return admission.Denied(err.Error()).WithWarnings(warnings...)
+	}
+
+	err = source.ApplyDefaults()
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, fmt.Errorf("apply default values: %w", err))
+	}
+
+	violations := policy.Evaluate(v.Rules, policy.NewWorkload(source))
+	if !enforcePolicy {
+		return admission.Allowed("").WithWarnings(append(warnings, violations.Messages()...)...)
+	}
+	warnings = append(warnings, violations.Warnings().Messages()...)
+
+	err = violations.Err()
+	if err != nil {
+		return admission.Denied(err.Error()).WithWarnings(warnings...)
+	}
+
+	return admission.Allowed("").WithWarnings(warnings...)
+}
+
+func decode(decoder admission.Decoder, kind string, raw runtime.RawExtension) (resource.Source, error) {
+	var source resource.Source
+
+	switch kind {
+	case "Application":
+		source = &nais_io_v1alpha1.Application{}
+	case "Naisjob":
+		source = &nais_io_v1.Naisjob{}
+	default:
+		return nil, fmt.Errorf("unsupported kind %q", kind)
+	}
+
+	err := decoder.DecodeRaw(raw, source)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", kind, err)
+	}
+
+	return source, nil
+}
+
+// workloadChanged returns true if the spec or the annotations of the workload, which policy rules are evaluated
+// against, differ between the old and new object of an update.
+func workloadChanged(object, oldObject runtime.RawExtension) (bool, error) {
+	type workload struct {
+		Metadata struct {
+			Annotations map[string]string `json:"annotations"`
+		} `json:"metadata"`
+		Spec map[string]any `json:"spec"`
+	}
+
+	var current, previous workload
+	err := json.Unmarshal(object.Raw, &current)
+	if err != nil {
+		return false, fmt.Errorf("decode object: %w", err)
+	}
+	err = json.Unmarshal(oldObject.Raw, &previous)
+	if err != nil {
+		return false, fmt.Errorf("decode old object: %w", err)
+	}
+
+	return !reflect.DeepEqual(current, previous), nil
+}
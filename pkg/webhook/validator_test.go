@@ -0,0 +1,132 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	nais_io_v1alpha1 "github.com/nais/liberator/pkg/apis/nais.io/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/nais/naiserator/pkg/annotations"
+	"github.com/nais/naiserator/pkg/naiserator/config"
+	"github.com/nais/naiserator/pkg/policy"
+	"github.com/nais/naiserator/pkg/test/fixtures"
+)
+
+func newValidator(t *testing.T) *Validator {
+	scheme := runtime.NewScheme()
+	err := nais_io_v1alpha1.AddToScheme(scheme)
+	assert.NoError(t, err)
+
+	return &Validator{
+		Decoder: admission.NewDecoder(scheme),
+		Rules: []config.PolicyRule{
+			{
+				Name:                   "registries",
+				Severity:               policy.SeverityError,
+				AllowedImageRegistries: []string{"europe-north1-docker.pkg.dev/"},
+			},
+		},
+	}
+}
+
+func admissionRequest(t *testing.T, operation admissionv1.Operation, object, oldObject *nais_io_v1alpha1.Application) admission.Request {
+	raw := func(app *nais_io_v1alpha1.Application) runtime.RawExtension {
+		if app == nil {
+			return runtime.RawExtension{}
+		}
+		data, err := json.Marshal(app)
+		assert.NoError(t, err)
+		return runtime.RawExtension{Raw: data}
+	}
+
+	return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: operation,
+		Kind:      metav1.GroupVersionKind{Group: "nais.io", Version: "v1alpha1", Kind: "Application"},
+		Namespace: object.GetNamespace(),
+		Object:    raw(object),
+		OldObject: raw(oldObject),
+	}}
+}
+
+func TestValidator_Policy(t *testing.T) {
+	validator := newValidator(t)
+
+	violating := fixtures.MinimalApplication()
+	violating.Spec.Image = "docker.io/navikt/myapplication:1.2.3"
+
+	t.Run("new workloads violating a rule are denied", func(t *testing.T) {
+		response := validator.Handle(context.Background(), admissionRequest(t, admissionv1.Create, violating, nil))
+		assert.False(t, response.Allowed)
+	})
+
+	t.Run("changes to the spec violating a rule are denied", func(t *testing.T) {
+		previous := violating.DeepCopy()
+		previous.Spec.Image = "docker.io/navikt/myapplication:1.2.2"
+		response := validator.Handle(context.Background(), admissionRequest(t, admissionv1.Update, violating, previous))
+		assert.False(t, response.Allowed)
+	})
+
+	t.Run("updates leaving the spec unchanged are allowed", func(t *testing.T) {
+		updated := violating.DeepCopy()
+		updated.SetFinalizers([]string{"naiserator.nais.io/finalizer"})
+		updated.SetLabels(map[string]string{"team": updated.GetNamespace()})
+		response := validator.Handle(context.Background(), admissionRequest(t, admissionv1.Update, updated, violating))
+		assert.True(t, response.Allowed)
+		assert.NotEmpty(t, response.Warnings)
+	})
+
+	t.Run("updates of workloads being deleted are allowed", func(t *testing.T) {
+		deleted := violating.DeepCopy()
+		now := metav1.Now()
+		deleted.SetDeletionTimestamp(&now)
+		updated := deleted.DeepCopy()
+		updated.SetFinalizers(nil)
+		updated.Spec.Image = "docker.io/navikt/myapplication:1.2.4"
+		response := validator.Handle(context.Background(), admissionRequest(t, admissionv1.Update, updated, deleted))
+		assert.True(t, response.Allowed)
+	})
+}
+
+func TestValidator_Annotations(t *testing.T) {
+	validator := newValidator(t)
+
+	invalid := fixtures.MinimalApplication(fixtures.WithAnnotation(annotations.RunAsUser, "root"))
+	invalid.Spec.Image = "europe-north1-docker.pkg.dev/nais/myapplication:1.2.3"
+
+	t.Run("new workloads with invalid values are denied", func(t *testing.T) {
+		response := validator.Handle(context.Background(), admissionRequest(t, admissionv1.Create, invalid, nil))
+		assert.False(t, response.Allowed)
+	})
+
+	t.Run("changing to an invalid value is denied", func(t *testing.T) {
+		previous := invalid.DeepCopy()
+		previous.GetAnnotations()[annotations.RunAsUser] = "1069"
+		response := validator.Handle(context.Background(), admissionRequest(t, admissionv1.Update, invalid, previous))
+		assert.False(t, response.Allowed)
+	})
+
+	t.Run("invalid values stored before are reported as warnings", func(t *testing.T) {
+		updated := invalid.DeepCopy()
+		updated.Spec.Image = "europe-north1-docker.pkg.dev/nais/myapplication:1.2.4"
+		response := validator.Handle(context.Background(), admissionRequest(t, admissionv1.Update, updated, invalid))
+		assert.True(t, response.Allowed)
+		assert.Contains(t, response.Warnings, "annotation nais.io/run-as-user: invalid value \"root\"; must be a non-negative integer")
+	})
+
+	t.Run("workloads with invalid values can be deleted", func(t *testing.T) {
+		deleted := invalid.DeepCopy()
+		now := metav1.Now()
+		deleted.SetDeletionTimestamp(&now)
+		deleted.SetFinalizers([]string{"naiserator.nais.io/finalizer"})
+		updated := deleted.DeepCopy()
+		updated.SetFinalizers(nil)
+		response := validator.Handle(context.Background(), admissionRequest(t, admissionv1.Update, updated, deleted))
+		assert.True(t, response.Allowed)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"maps"
+	"strings"
 	"time"
 
 	sql_cnrm_cloud_google_com_v1beta1 "github.com/nais/liberator/pkg/apis/sql.cnrm.cloud.google.com/v1beta1"
@@ -11,7 +12,11 @@ import (
 	liberator_scheme "github.com/nais/liberator/pkg/scheme"
 	"github.com/nais/naiserator/pkg/resourcecreator/resource"
 	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -19,6 +24,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/selection"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 )
 
 // AnnotateIfExists copies annotations of the given resource into the existing resource.
@@ -77,6 +83,10 @@ func CreateOrUpdate(ctx context.Context, cli client.Client, scheme *runtime.Sche
 
 		err = cli.Get(ctx, objectKey, existing.(client.Object))
 
+		if err == nil && isRecreating(existing.(client.Object)) {
+			return awaitRecreate(ctx, cli, existing.(client.Object), resource, time.Now())
+		}
+
 		if errors.IsNotFound(err) {
 			err = cli.Create(ctx, resource)
 		} else if err == nil {
@@ -97,6 +107,10 @@ func CreateOrUpdate(ctx context.Context, cli client.Client, scheme *runtime.Sche
 				return err
 			}
 			err = cli.Update(ctx, resource)
+			if propagation, ok := recreatePropagation(resource); ok && isImmutableFieldError(err) {
+				log.Infof("Recreating %s because of changes to immutable fields: %s", liberator_scheme.TypeName(resource), err)
+				return recreate(ctx, cli, existing.(client.Object), resource, propagation)
+			}
 		}
 
 		if err != nil {
@@ -106,38 +120,166 @@ func CreateOrUpdate(ctx context.Context, cli client.Client, scheme *runtime.Sche
 	}
 }
 
+const (
+	// RecreatingAnnotation is set to the time a resource was deleted so that it could be created again.
+	RecreatingAnnotation = "naiserator.nais.io/recreating"
+
+	// RecreateTimeout is how long a recreated resource may take to be deleted before it is reported as an error.
+	RecreateTimeout = 10 * time.Minute
+
+	// RecreateWait is how long a single synchronization waits for a recreated resource to be deleted.
+	// If it takes longer, ErrRecreatePending is returned and the resource is created on a later attempt.
+	RecreateWait = 3 * time.Second
+
+	recreatePollInterval = 250 * time.Millisecond
+)
+
+// ErrRecreatePending is returned while a resource that must be recreated is being deleted.
+// The caller should try again later; the resource is created once the old one is gone.
+type ErrRecreatePending struct {
+	Kind string
+	Name string
+}
+
+func (e *ErrRecreatePending) Error() string {
+	return fmt.Sprintf("waiting for %s %s to be deleted before recreating it", e.Kind, e.Name)
+}
+
+// recreate deletes the existing resource and creates the desired resource once it is gone.
+// The resource is annotated first, so that later attempts only wait for deletions started here.
+// With foreground propagation, dependents are gone before the resource disappears.
+func recreate(ctx context.Context, cli client.Client, existing, resource client.Object, propagation metav1.DeletionPropagation) error {
+	patchSource := client.MergeFrom(existing.DeepCopyObject().(client.Object))
+	annotations := existing.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[RecreatingAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	existing.SetAnnotations(annotations)
+	err := cli.Patch(ctx, existing, patchSource)
+	if errors.IsNotFound(err) {
+		return create(ctx, cli, resource)
+	} else if err != nil {
+		return fmt.Errorf("mark %s for recreation: %w", liberator_scheme.TypeName(existing), err)
+	}
+
+	deleteOptions := &client.DeleteOptions{}
+	client.PropagationPolicy(propagation).ApplyToDelete(deleteOptions)
+	err = cli.Delete(ctx, existing, deleteOptions)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	return awaitRecreate(ctx, cli, existing, resource, time.Now())
+}
+
+// isRecreating returns true if the resource is being deleted by an earlier recreate.
+// Resources that are being deleted by others are updated as usual.
+func isRecreating(existing client.Object) bool {
+	_, found := existing.GetAnnotations()[RecreatingAnnotation]
+	return found && existing.GetDeletionTimestamp() != nil
+}
+
+// awaitRecreate waits up to RecreateWait for a resource deleted by recreate to be gone, and then creates the desired
+// resource. ErrRecreatePending is returned if it is still there, or an error if the deletion has been held back for
+// longer than RecreateTimeout, e.g. by a finalizer.
+func awaitRecreate(ctx context.Context, cli client.Client, existing, resource client.Object, now time.Time) error {
+	deleted, err := time.Parse(time.RFC3339, existing.GetAnnotations()[RecreatingAnnotation])
+	if err != nil || now.Sub(deleted) > RecreateTimeout {
+		return fmt.Errorf(
+			"%s %s was deleted to be recreated, but is still not gone after %s; check its finalizers",
+			liberator_scheme.TypeName(existing), existing.GetName(), RecreateTimeout,
+		)
+	}
+
+	pending := &ErrRecreatePending{
+		Kind: liberator_scheme.TypeName(existing),
+		Name: existing.GetName(),
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, RecreateWait)
+	defer cancel()
+	objectKey := client.ObjectKeyFromObject(existing)
+
+	for {
+		err = cli.Get(waitCtx, objectKey, existing)
+		if errors.IsNotFound(err) {
+			return create(ctx, cli, resource)
+		}
+		if waitCtx.Err() != nil {
+			return pending
+		}
+		if err != nil {
+			return fmt.Errorf("get %s while recreating: %w", liberator_scheme.TypeName(existing), err)
+		}
+
+		select {
+		case <-waitCtx.Done():
+			return pending
+		case <-time.After(recreatePollInterval):
+		}
+	}
+}
+
+// create creates a resource that may have been read from the cluster before it was deleted.
+func create(ctx context.Context, cli client.Client, resource client.Object) error {
+	resource.SetResourceVersion("")
+	resource.SetUID("")
+	return cli.Create(ctx, resource)
+}
+
+// recreatePropagation returns how the existing resource is deleted when an update is rejected because it changes
+// immutable fields, and false for kinds that must not be recreated. Other kinds, such as Deployments and CNRM
+// resources, hold state that would be lost, and the update fails instead.
+func recreatePropagation(resource client.Object) (metav1.DeletionPropagation, bool) {
+	switch resource.(type) {
+	case *batchv1.Job, *corev1.Service, *policyv1.PodDisruptionBudget, *rbacv1.RoleBinding:
+		return metav1.DeletePropagationForeground, true
+	case *appsv1.StatefulSet:
+		// Pods and persistent volume claims are orphaned and adopted by the new StatefulSet, which replaces the pods
+		// in a rolling update. Claims for new volume claim templates are created, the others are reused.
+		return metav1.DeletePropagationOrphan, true
+	default:
+		return "", false
+	}
+}
+
+// isImmutableFieldError returns true if an update was rejected because it changes immutable fields.
+// RoleBindings and StatefulSets report this with messages of their own.
+func isImmutableFieldError(err error) bool {
+	if !errors.IsInvalid(err) {
+		return false
+	}
+	message := err.Error()
+	return strings.Contains(message, "field is immutable") ||
+		strings.Contains(message, "cannot change roleRef") ||
+		strings.Contains(message, "updates to statefulset spec for fields other than")
+}
+
+// CreateOrRecreate creates the resource, deleting any existing resource first.
+// It waits up to RecreateWait for the deletion, and returns ErrRecreatePending until the existing resource is gone.
 func CreateOrRecreate(ctx context.Context, cli client.Client, scheme *runtime.Scheme, resource client.Object) func() error {
 	return func() error {
 		log.Infof("CreateOrRecreate %s", liberator_scheme.TypeName(resource))
-		deleteOptions := &client.DeleteOptions{}
-		client.PropagationPolicy(metav1.DeletePropagationBackground).ApplyToDelete(deleteOptions)
-		err := cli.Delete(ctx, resource, deleteOptions)
-		if err != nil && !errors.IsNotFound(err) {
-			return err
-		}
-
 		existing, err := scheme.New(resource.GetObjectKind().GroupVersionKind())
 		if err != nil {
 			return fmt.Errorf("internal error: %w", err)
 		}
-		objectKey := client.ObjectKeyFromObject(resource)
-		timedOut := time.Now().Add(time.Minute + time.Duration(5))
+		existingObj := existing.(client.Object)
 
-		for {
-			err = cli.Get(ctx, objectKey, existing.(client.Object))
-			if errors.IsNotFound(err) {
-				break
-			}
-			if err != nil {
-				return fmt.Errorf("internal error: %v", err)
-			}
-			if time.Now().After(timedOut) {
-				return fmt.Errorf("timed out waiting for deletion of %v/%v", resource.GetObjectKind(), resource.GetName())
-			}
-			time.Sleep(1 * time.Second)
+		err = cli.Get(ctx, client.ObjectKeyFromObject(resource), existingObj)
+		if errors.IsNotFound(err) {
+			return cli.Create(ctx, resource)
+		}
+		if err != nil {
+			return err
+		}
+
+		if isRecreating(existingObj) {
+			return awaitRecreate(ctx, cli, existingObj, resource, time.Now())
 		}
 
-		return cli.Create(ctx, resource)
+		return recreate(ctx, cli, existingObj, resource, metav1.DeletePropagationForeground)
 	}
 }
 
@@ -180,6 +322,11 @@ func FindAll(ctx context.Context, cli client.Client, types []client.ObjectList,
 
 	for _, obj := range types {
 		err = cli.List(ctx, obj, listopt)
+		if meta.IsNoMatchError(err) {
+			// The kind has been uninstalled from the cluster while Naiserator is running.
+			log.Warnf("Skipping %T when looking for unreferenced resources: %s", obj, err)
+			continue
+		}
 		if err != nil {
 			return nil, fmt.Errorf("list %T: %w", obj, err)
 		}
@@ -197,6 +344,72 @@ func FindAll(ctx context.Context, cli client.Client, types []client.ObjectList,
 	return withOwnerReference(source, resources), nil
 }
 
+// OwnerUIDIndex is a cache field index with the UIDs of an object's owners.
+const OwnerUIDIndex = "metadata.ownerReferences.uid"
+
+// IndexOwnerUID registers OwnerUIDIndex in the cache for the item type of every list type.
+func IndexOwnerUID(ctx context.Context, indexer client.FieldIndexer, scheme *runtime.Scheme, types []client.ObjectList) error {
+	for _, list := range types {
+		listGVK, err := apiutil.GVKForObject(list, scheme)
+		if err != nil {
+			return err
+		}
+
+		itemGVK := listGVK.GroupVersion().WithKind(strings.TrimSuffix(listGVK.Kind, "List"))
+		item, err := scheme.New(itemGVK)
+		if err != nil {
+			return err
+		}
+
+		obj, ok := item.(client.Object)
+		if !ok {
+			return fmt.Errorf("%s is not an object", itemGVK)
+		}
+
+		err = indexer.IndexField(ctx, obj, OwnerUIDIndex, ownerUIDs)
+		if err != nil {
+			return fmt.Errorf("index %s by owner: %w", itemGVK.Kind, err)
+		}
+	}
+
+	return nil
+}
+
+func ownerUIDs(obj client.Object) []string {
+	refs := obj.GetOwnerReferences()
+	uids := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		uids = append(uids, string(ref.UID))
+	}
+	return uids
+}
+
+// FindOwned finds all Kubernetes resources owned by source for all specified types, using OwnerUIDIndex.
+// Unlike FindAll, resources are found even if they don't have the 'app=NAME' label.
+// The client must read from a cache where IndexOwnerUID has been called.
+func FindOwned(ctx context.Context, cli client.Client, types []client.ObjectList, source resource.Source) ([]runtime.Object, error) {
+	resources := make([]runtime.Object, 0)
+
+	for _, obj := range types {
+		err := cli.List(ctx, obj, client.MatchingFields{OwnerUIDIndex: string(source.GetUID())})
+		if meta.IsNoMatchError(err) {
+			// The kind has been uninstalled from the cluster while Naiserator is running.
+			log.Warnf("Skipping %T when looking for unreferenced resources: %s", obj, err)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("list %T: %w", obj, err)
+		}
+
+		_ = meta.EachListItem(obj, func(item runtime.Object) error {
+			resources = append(resources, item)
+			return nil
+		})
+	}
+
+	return resources, nil
+}
+
 // KeepOwnerReference ensures that if ownerReference is set on the source object,
 // it is copied to the destination object.
 //
@@ -1,7 +1,9 @@
 package updater_test
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	google_storage_crd "github.com/nais/liberator/pkg/apis/storage.cnrm.cloud.google.com/v1beta1"
 	"github.com/nais/naiserator/pkg/resourcecreator/google"
@@ -9,7 +11,17 @@ import (
 	"github.com/nais/naiserator/pkg/util"
 	"github.com/nais/naiserator/updater"
 	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 )
 
 func fixture() *google_storage_crd.StorageBucket {
@@ -150,3 +162,261 @@ func TestKeepOwnerReferenceNoOwner(t *testing.T) {
 	assert.Len(t, resource.OwnerReferences, 1)
 	assert.Equal(t, "myapplication", resource.OwnerReferences[0].Name)
 }
+
+// fakeIndexer registers indexes on a fake client builder.
+type fakeIndexer struct {
+	builder *fake.ClientBuilder
+}
+
+func (f *fakeIndexer) IndexField(_ context.Context, obj client.Object, field string, extractValue client.IndexerFunc) error {
+	f.builder = f.builder.WithIndex(obj, field, extractValue)
+	return nil
+}
+
+func TestFindOwned(t *testing.T) {
+	ctx := context.Background()
+	app := fixtures.MinimalApplication()
+	app.SetUID("app-uid")
+
+	owned := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "without-app-label",
+			Namespace:       app.GetNamespace(),
+			OwnerReferences: []metav1.OwnerReference{app.GetOwnerReference()},
+		},
+	}
+	other := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "not-owned",
+			Namespace: app.GetNamespace(),
+			Labels:    map[string]string{"app": app.GetName()},
+		},
+	}
+
+	types := []client.ObjectList{&corev1.SecretList{}, &corev1.ServiceList{}}
+	indexer := &fakeIndexer{builder: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(owned, other)}
+	assert.NoError(t, updater.IndexOwnerUID(ctx, indexer, scheme.Scheme, types))
+
+	resources, err := updater.FindOwned(ctx, indexer.builder.Build(), types, app)
+	assert.NoError(t, err)
+	if assert.Len(t, resources, 1) {
+		assert.Equal(t, "without-app-label", resources[0].(*corev1.Secret).GetName())
+	}
+}
+
+func TestCreateOrRecreate(t *testing.T) {
+	ctx := context.Background()
+	existing := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "namespace",
+		},
+		Data: map[string]string{"version": "old"},
+	}
+	desired := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "namespace",
+		},
+		Data: map[string]string{"version": "new"},
+	}
+
+	t.Run("resource is created again in the same pass", func(t *testing.T) {
+		cli := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(existing.DeepCopy()).Build()
+
+		err := updater.CreateOrRecreate(ctx, cli, scheme.Scheme, desired.DeepCopy())()
+		assert.NoError(t, err)
+
+		created := &corev1.ConfigMap{}
+		assert.NoError(t, cli.Get(ctx, client.ObjectKeyFromObject(desired), created))
+		assert.Equal(t, "new", created.Data["version"])
+	})
+
+	t.Run("resource held by a finalizer is created once it is gone", func(t *testing.T) {
+		held := existing.DeepCopy()
+		held.Finalizers = []string{"test/finalizer"}
+		cli := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(held).Build()
+
+		// The existing resource is deleted, but held by its finalizer.
+		pending := &updater.ErrRecreatePending{}
+		err := updater.CreateOrRecreate(shortContext(t), cli, scheme.Scheme, desired.DeepCopy())()
+		assert.ErrorAs(t, err, &pending)
+
+		err = updater.CreateOrRecreate(shortContext(t), cli, scheme.Scheme, desired.DeepCopy())()
+		assert.ErrorAs(t, err, &pending)
+
+		// Once the finalizer is removed, the resource is created again.
+		deleting := &corev1.ConfigMap{}
+		assert.NoError(t, cli.Get(ctx, client.ObjectKeyFromObject(desired), deleting))
+		assert.NotNil(t, deleting.GetDeletionTimestamp())
+		deleting.SetFinalizers(nil)
+		assert.NoError(t, cli.Update(ctx, deleting))
+
+		err = updater.CreateOrRecreate(ctx, cli, scheme.Scheme, desired.DeepCopy())()
+		assert.NoError(t, err)
+
+		created := &corev1.ConfigMap{}
+		assert.NoError(t, cli.Get(ctx, client.ObjectKeyFromObject(desired), created))
+		assert.Equal(t, "new", created.Data["version"])
+	})
+}
+
+// shortContext returns a context that expires long before updater.RecreateWait.
+func shortContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestCreateOrUpdateImmutableFields(t *testing.T) {
+	ctx := context.Background()
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "namespace"}}
+	owner := metav1.OwnerReference{Kind: "Application", Name: "foo", UID: "app-uid"}
+	objectMeta := metav1.ObjectMeta{
+		Name:            "foo",
+		Namespace:       "namespace",
+		OwnerReferences: []metav1.OwnerReference{owner},
+	}
+
+	rejectUpdates := interceptor.Funcs{
+		Update: func(ctx context.Context, cli client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+			return k8serrors.NewInvalid(schema.GroupKind{Kind: "Test"}, obj.GetName(), field.ErrorList{
+				field.Invalid(field.NewPath("spec", "selector"), nil, "field is immutable"),
+			})
+		},
+	}
+
+	t.Run("allowed kinds are recreated", func(t *testing.T) {
+		existing := &corev1.Service{ObjectMeta: *objectMeta.DeepCopy()}
+		existing.Finalizers = []string{"test/finalizer"}
+		desired := &corev1.Service{
+			TypeMeta:   metav1.TypeMeta{Kind: "Service", APIVersion: "v1"},
+			ObjectMeta: *objectMeta.DeepCopy(),
+		}
+		cli := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(namespace, existing).WithInterceptorFuncs(rejectUpdates).Build()
+
+		pending := &updater.ErrRecreatePending{}
+		err := updater.CreateOrUpdate(shortContext(t), cli, scheme.Scheme, desired.DeepCopy())()
+		assert.ErrorAs(t, err, &pending)
+
+		deleting := &corev1.Service{}
+		assert.NoError(t, cli.Get(ctx, client.ObjectKeyFromObject(desired), deleting))
+		assert.NotNil(t, deleting.GetDeletionTimestamp())
+		assert.Contains(t, deleting.GetAnnotations(), updater.RecreatingAnnotation)
+
+		// Waiting for the deletion is given up after a while.
+		aged := deleting.DeepCopy()
+		aged.Annotations[updater.RecreatingAnnotation] = time.Now().Add(-2 * updater.RecreateTimeout).UTC().Format(time.RFC3339)
+		assert.NoError(t, cli.Patch(ctx, aged, client.MergeFrom(deleting)))
+		err = updater.CreateOrUpdate(ctx, cli, scheme.Scheme, desired.DeepCopy())()
+		assert.Error(t, err)
+		assert.NotErrorAs(t, err, &pending)
+	})
+
+	t.Run("role bindings with a changed roleRef are recreated in the same pass", func(t *testing.T) {
+		existing := &rbacv1.RoleBinding{
+			ObjectMeta: *objectMeta.DeepCopy(),
+			RoleRef:    rbacv1.RoleRef{APIGroup: "rbac.authorization.k8s.io", Kind: "ClusterRole", Name: "old"},
+		}
+		desired := &rbacv1.RoleBinding{
+			TypeMeta:   metav1.TypeMeta{Kind: "RoleBinding", APIVersion: "rbac.authorization.k8s.io/v1"},
+			ObjectMeta: *objectMeta.DeepCopy(),
+			RoleRef:    rbacv1.RoleRef{APIGroup: "rbac.authorization.k8s.io", Kind: "ClusterRole", Name: "new"},
+		}
+		rejectRoleRef := interceptor.Funcs{
+			Update: func(ctx context.Context, cli client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+				return k8serrors.NewInvalid(schema.GroupKind{Group: "rbac.authorization.k8s.io", Kind: "RoleBinding"}, obj.GetName(), field.ErrorList{
+					field.Invalid(field.NewPath("roleRef"), nil, "cannot change roleRef"),
+				})
+			},
+		}
+		cli := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(namespace, existing).WithInterceptorFuncs(rejectRoleRef).Build()
+
+		err := updater.CreateOrUpdate(ctx, cli, scheme.Scheme, desired.DeepCopy())()
+		assert.NoError(t, err)
+
+		created := &rbacv1.RoleBinding{}
+		assert.NoError(t, cli.Get(ctx, client.ObjectKeyFromObject(desired), created))
+		assert.Equal(t, "new", created.RoleRef.Name)
+		assert.NotContains(t, created.GetAnnotations(), updater.RecreatingAnnotation)
+	})
+
+	t.Run("statefulsets are recreated without deleting their pods and volumes", func(t *testing.T) {
+		existing := &appsv1.StatefulSet{ObjectMeta: *objectMeta.DeepCopy()}
+		desired := &appsv1.StatefulSet{
+			TypeMeta:   metav1.TypeMeta{Kind: "StatefulSet", APIVersion: "apps/v1"},
+			ObjectMeta: *objectMeta.DeepCopy(),
+			Spec: appsv1.StatefulSetSpec{
+				VolumeClaimTemplates: []corev1.PersistentVolumeClaim{{ObjectMeta: metav1.ObjectMeta{Name: "data"}}},
+			},
+		}
+		var propagation metav1.DeletionPropagation
+		rejectSpec := interceptor.Funcs{
+			Update: func(ctx context.Context, cli client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+				return k8serrors.NewInvalid(schema.GroupKind{Group: "apps", Kind: "StatefulSet"}, obj.GetName(), field.ErrorList{
+					field.Forbidden(field.NewPath("spec"), "updates to statefulset spec for fields other than 'replicas', 'ordinals', 'template', 'updateStrategy', 'revisionHistoryLimit', 'persistentVolumeClaimRetentionPolicy' and 'minReadySeconds' are forbidden"),
+				})
+			},
+			Delete: func(ctx context.Context, cli client.WithWatch, obj client.Object, opts ...client.DeleteOption) error {
+				deleteOptions := &client.DeleteOptions{}
+				deleteOptions.ApplyOptions(opts)
+				propagation = *deleteOptions.PropagationPolicy
+				return cli.Delete(ctx, obj, opts...)
+			},
+		}
+		cli := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(namespace, existing).WithInterceptorFuncs(rejectSpec).Build()
+
+		err := updater.CreateOrUpdate(ctx, cli, scheme.Scheme, desired.DeepCopy())()
+		assert.NoError(t, err)
+		assert.Equal(t, metav1.DeletePropagationOrphan, propagation)
+
+		created := &appsv1.StatefulSet{}
+		assert.NoError(t, cli.Get(ctx, client.ObjectKeyFromObject(desired), created))
+		assert.Len(t, created.Spec.VolumeClaimTemplates, 1)
+	})
+
+	t.Run("other kinds fail the update", func(t *testing.T) {
+		existing := &appsv1.Deployment{ObjectMeta: *objectMeta.DeepCopy()}
+		desired := &appsv1.Deployment{
+			TypeMeta:   metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+			ObjectMeta: *objectMeta.DeepCopy(),
+		}
+		cli := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(namespace, existing).WithInterceptorFuncs(rejectUpdates).Build()
+
+		err := updater.CreateOrUpdate(ctx, cli, scheme.Scheme, desired.DeepCopy())()
+		assert.True(t, k8serrors.IsInvalid(err))
+
+		live := &appsv1.Deployment{}
+		assert.NoError(t, cli.Get(ctx, client.ObjectKeyFromObject(desired), live))
+		assert.Nil(t, live.GetDeletionTimestamp())
+	})
+}
+
+func TestCreateOrUpdateDeletedByOthers(t *testing.T) {
+	ctx := context.Background()
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "namespace"}}
+	now := metav1.NewTime(time.Now().Truncate(time.Second))
+	existing := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "foo",
+			Namespace:         "namespace",
+			Finalizers:        []string{"test/finalizer"},
+			DeletionTimestamp: &now,
+			OwnerReferences:   []metav1.OwnerReference{{Kind: "Application", Name: "foo", UID: "app-uid"}},
+		},
+	}
+	desired := existing.DeepCopy()
+	desired.TypeMeta = metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"}
+	// The fake client rejects updates that change the deletion timestamp; the API server ignores them.
+	desired.Data = map[string]string{"version": "new"}
+
+	cli := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(namespace, existing).Build()
+
+	// Resources held from deletion by someone else's finalizer don't block the synchronization.
+	err := updater.CreateOrUpdate(ctx, cli, scheme.Scheme, desired)()
+	assert.NoError(t, err)
+}